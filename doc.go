@@ -155,7 +155,8 @@
 //	Opts{W: 128, H: 64}  // 128×64 (smaller displays)
 //	Opts{W: 256, H: 128} // 256×128 (extended height, if available)
 //
-// Width must be even and ≤480. Height must be ≤128.
+// Width must be ≤480 and height ≤128. Odd widths are padded internally with
+// one dark column, so Write data uses the width rounded up to even.
 //
 // # Datasheet
 //
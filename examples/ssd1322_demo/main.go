@@ -74,8 +74,9 @@ func main() {
 
 	// Create display device
 	dev, err := ssd1322.NewSPI(b, pin, &ssd1322.Opts{
-		W: *width,
-		H: *height,
+		W:     *width,
+		H:     *height,
+		SPIHz: *spiHz,
 	})
 	if err != nil {
 		log.Fatalf("Failed to create display: %v", err)
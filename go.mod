@@ -5,3 +5,5 @@ go 1.25.6
 require periph.io/x/conn/v3 v3.7.2
 
 require periph.io/x/host/v3 v3.8.5
+
+require golang.org/x/image v0.44.0
@@ -0,0 +1,92 @@
+package image4bit
+
+import (
+	"image"
+	"math"
+)
+
+// DrawCircle draws the one pixel wide outline of the circle of radius r
+// centered on (cx, cy) onto p with color c, using the midpoint circle
+// algorithm. A zero radius draws the center pixel and a negative one draws
+// nothing. Pixels outside p's bounds are skipped, as by SetGray4.
+func DrawCircle(p *HorizontalNibble, cx, cy, r int, c Gray4) {
+	if !circleVisible(p, cx, cy, r) {
+		return
+	}
+	midpointCircle(r, func(x, y int) {
+		for _, pt := range octants(x, y) {
+			p.SetGray4(cx+pt.X, cy+pt.Y, c)
+		}
+	})
+}
+
+// FillCircle fills the disc of radius r centered on (cx, cy) onto p with
+// color c, covering the outline DrawCircle draws and every pixel inside
+// it. Each row of the disc is filled as a rectangle clipped to p's
+// bounds.
+func FillCircle(p *HorizontalNibble, cx, cy, r int, c Gray4) {
+	if !circleVisible(p, cx, cy, r) {
+		return
+	}
+	midpointCircle(r, func(x, y int) {
+		p.FillRect(image.Rect(cx-x, cy-y, cx+x+1, cy-y+1), c)
+		p.FillRect(image.Rect(cx-x, cy+y, cx+x+1, cy+y+1), c)
+		p.FillRect(image.Rect(cx-y, cy-x, cx+y+1, cy-x+1), c)
+		p.FillRect(image.Rect(cx-y, cy+x, cx+y+1, cy+x+1), c)
+	})
+}
+
+// DrawArc draws the part of the DrawCircle outline that runs clockwise from
+// the start angle to the end angle, in degrees. Angles are measured from
+// the positive x axis and, since y grows downwards, increase clockwise, so
+// 90° points straight down. An end angle below the start wraps around
+// through 0°, and a sweep of 360° or more draws the whole circle.
+func DrawArc(p *HorizontalNibble, cx, cy, r int, start, end float64, c Gray4) {
+	if !circleVisible(p, cx, cy, r) {
+		return
+	}
+	sweep := end - start
+	if sweep < 360 {
+		sweep = math.Mod(math.Mod(sweep, 360)+360, 360)
+	}
+	midpointCircle(r, func(x, y int) {
+		for _, pt := range octants(x, y) {
+			deg := math.Atan2(float64(pt.Y), float64(pt.X)) * 180 / math.Pi
+			if math.Mod(math.Mod(deg-start, 360)+360, 360) <= sweep {
+				p.SetGray4(cx+pt.X, cy+pt.Y, c)
+			}
+		}
+	})
+}
+
+// circleVisible reports whether the circle of radius r centered on
+// (cx, cy) exists and its bounding box overlaps p.
+func circleVisible(p *HorizontalNibble, cx, cy, r int) bool {
+	return r >= 0 && image.Rect(cx-r, cy-r, cx+r+1, cy+r+1).Overlaps(p.Rect)
+}
+
+// midpointCircle calls plot for each point (x, y) of the first octant of a
+// circle of radius r centered on the origin, from (r, 0) until x < y.
+func midpointCircle(r int, plot func(x, y int)) {
+	x, y := r, 0
+	d := 1 - r
+	for x >= y {
+		plot(x, y)
+		y++
+		if d < 0 {
+			d += 2*y + 1
+		} else {
+			x--
+			d += 2*(y-x) + 1
+		}
+	}
+}
+
+// octants returns the eight reflections of (x, y) across the axes and
+// diagonals.
+func octants(x, y int) [8]image.Point {
+	return [8]image.Point{
+		{x, y}, {y, x}, {-y, x}, {-x, y},
+		{-x, -y}, {-y, -x}, {y, -x}, {x, -y},
+	}
+}
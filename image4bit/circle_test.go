@@ -0,0 +1,110 @@
+package image4bit
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDrawCircle(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 12, 12))
+	DrawCircle(img, 5, 5, 3, Gray4{Y: 15})
+
+	// Radius 3 steps through (3, 0), (3, 1) and (2, 2) in the first
+	// octant; each is mirrored into all eight
+	var want []image.Point
+	for _, pt := range []image.Point{{3, 0}, {3, 1}, {2, 2}} {
+		for _, m := range octants(pt.X, pt.Y) {
+			want = append(want, image.Pt(5+m.X, 5+m.Y))
+		}
+	}
+	for _, pt := range want {
+		if got := img.Gray4At(pt.X, pt.Y).Y; got != 15 {
+			t.Errorf("Gray4At(%d, %d).Y = %d, want 15", pt.X, pt.Y, got)
+		}
+	}
+	if got := len(litPixels(img)); got != 16 {
+		t.Errorf("circle lit %d pixels, want 16", got)
+	}
+	if got := img.Gray4At(5, 5).Y; got != 0 {
+		t.Errorf("center Gray4At(5, 5).Y = %d, want 0", got)
+	}
+}
+
+func TestFillCircle(t *testing.T) {
+	outline := NewHorizontalNibble(image.Rect(0, 0, 12, 12))
+	DrawCircle(outline, 5, 5, 4, Gray4{Y: 15})
+	img := NewHorizontalNibble(image.Rect(0, 0, 12, 12))
+	FillCircle(img, 5, 5, 4, Gray4{Y: 15})
+
+	// Each row is lit from the outline's leftmost pixel to its rightmost
+	for y := 0; y < 12; y++ {
+		x0, x1 := -1, -1
+		for x := 0; x < 12; x++ {
+			if outline.Gray4At(x, y).Y != 0 {
+				if x0 < 0 {
+					x0 = x
+				}
+				x1 = x
+			}
+		}
+		for x := 0; x < 12; x++ {
+			want := uint8(0)
+			if x0 >= 0 && x >= x0 && x <= x1 {
+				want = 15
+			}
+			if got := img.Gray4At(x, y).Y; got != want {
+				t.Errorf("Gray4At(%d, %d).Y = %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestDrawArc(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 12, 12))
+
+	// 0° to 90° is the lower right quarter, as y grows downwards
+	DrawArc(img, 5, 5, 3, 0, 90, Gray4{Y: 15})
+	for _, pt := range []image.Point{{8, 5}, {8, 6}, {7, 7}, {6, 8}, {5, 8}} {
+		if got := img.Gray4At(pt.X, pt.Y).Y; got != 15 {
+			t.Errorf("Gray4At(%d, %d).Y = %d, want 15", pt.X, pt.Y, got)
+		}
+	}
+	if got := len(litPixels(img)); got != 5 {
+		t.Errorf("quarter arc lit %d pixels, want 5", got)
+	}
+
+	// Wrapping through 0° takes the upper right and lower right eighths
+	img.Fill(Gray4{})
+	DrawArc(img, 5, 5, 3, 315, 45, Gray4{Y: 15})
+	for _, pt := range []image.Point{{7, 3}, {8, 4}, {8, 5}, {8, 6}, {7, 7}} {
+		if got := img.Gray4At(pt.X, pt.Y).Y; got != 15 {
+			t.Errorf("Gray4At(%d, %d).Y = %d, want 15", pt.X, pt.Y, got)
+		}
+	}
+	if got := len(litPixels(img)); got != 5 {
+		t.Errorf("wrapped arc lit %d pixels, want 5", got)
+	}
+
+	// A full sweep matches DrawCircle
+	img.Fill(Gray4{})
+	DrawArc(img, 5, 5, 3, -90, 270, Gray4{Y: 15})
+	if got := len(litPixels(img)); got != 16 {
+		t.Errorf("full arc lit %d pixels, want 16", got)
+	}
+}
+
+func TestCircleOutOfBounds(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 8, 8))
+	c := Gray4{Y: 15}
+	DrawCircle(img, -20, -20, 5, c)
+	FillCircle(img, 30, 4, 6, c)
+	DrawArc(img, 4, 40, 10, 0, 360, c)
+	DrawCircle(img, 4, 4, -1, c)
+	if got := litPixels(img); len(got) != 0 {
+		t.Errorf("lit pixels = %v, want none", got)
+	}
+
+	// Partly visible circles are clipped
+	FillCircle(img, 0, 0, 20, c)
+	DrawCircle(img, 7, 7, 9, c)
+}
@@ -0,0 +1,131 @@
+package image4bit
+
+import (
+	"image"
+)
+
+// DitherFloydSteinberg quantizes src into dst with Floyd-Steinberg error
+// diffusion, which approximates intermediate shades with patterns of the
+// two nearest levels instead of the banding of Gray4Model.
+//
+// Each pixel is first reduced to its luminance (0.299 R + 0.587 G +
+// 0.114 B), then processed left to right, top to bottom, passing 7/16,
+// 3/16, 5/16 and 1/16 of its quantization error to the right, bottom-left,
+// bottom and bottom-right neighbours. The accumulated value is clamped to
+// the 8-bit range before quantizing, so errors cannot run away in
+// saturated areas.
+//
+// Only the pixels in both dst.Rect and src.Bounds(), at the same
+// coordinates, are written, and errors are not carried past the edges of
+// that region, so the result depends only on the src pixels inside it.
+func DitherFloydSteinberg(dst *HorizontalNibble, src image.Image) {
+	r := dst.Rect.Intersect(src.Bounds())
+	if r.Empty() {
+		return
+	}
+	gray, _ := src.(*image.Gray)
+
+	// Accumulated errors in 1/16ths, with a guard column on each side
+	cur := make([]int, r.Dx()+2)
+	next := make([]int, r.Dx()+2)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		clear(next)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := x - r.Min.X + 1
+			v := luminance(src, gray, x, y) + cur[i]/16
+			v = max(0, min(255, v))
+			level := (v*15 + 127) / 255
+			dst.setNibble(x, y, uint8(level))
+			e := v - level*0x11
+			cur[i+1] += 7 * e
+			next[i-1] += 3 * e
+			next[i] += 5 * e
+			next[i+1] += e
+		}
+		cur, next = next, cur
+	}
+}
+
+// luminance returns the 8-bit luminance of the src pixel at (x, y), reading
+// gray directly when src is an *image.Gray.
+func luminance(src image.Image, gray *image.Gray, x, y int) int {
+	if gray != nil {
+		return int(gray.Pix[gray.PixOffset(x, y)])
+	}
+	r, g, b, _ := src.At(x, y).RGBA()
+	return int((299*r + 587*g + 114*b + 500) / 1000 >> 8)
+}
+
+// BayerSize selects the threshold matrix used by DitherOrdered.
+type BayerSize int
+
+const (
+	Bayer4x4 BayerSize = iota // 4×4 matrix, 16 threshold steps
+	Bayer8x8                  // 8×8 matrix, 64 threshold steps
+)
+
+// bayer4 is the 4×4 Bayer index matrix; bayer8 is derived from it.
+var (
+	bayer4 = [][]int{
+		{0, 8, 2, 10},
+		{12, 4, 14, 6},
+		{3, 11, 1, 9},
+		{15, 7, 13, 5},
+	}
+	bayer8 = expandBayer(bayer4)
+)
+
+// expandBayer returns the Bayer index matrix of twice the size of m.
+func expandBayer(m [][]int) [][]int {
+	n := len(m)
+	out := make([][]int, 2*n)
+	for y := range out {
+		out[y] = make([]int, 2*n)
+		for x := range out[y] {
+			// Quadrant offsets 0, 2, 3 and 1, as in the 2×2 matrix
+			q := [2][2]int{{0, 2}, {3, 1}}[y/n][x/n]
+			out[y][x] = 4*m[y%n][x%n] + q
+		}
+	}
+	return out
+}
+
+// DitherOrdered quantizes src into dst with ordered dithering: each pixel's
+// luminance is offset by the entry of a Bayer threshold matrix for its
+// position before rounding down to one of the 16 levels, so intermediate
+// shades become a fixed pattern of the two nearest levels.
+//
+// Unlike DitherFloydSteinberg, every pixel depends only on its own source
+// value and position, so unchanged parts of an animation keep exactly the
+// same pattern from frame to frame. The matrix is aligned with the origin,
+// not with the image bounds. Levels that are exactly representable (multiples
+// of 0x11 in 8 bits) are reproduced unchanged.
+//
+// Only the pixels in both dst.Rect and src.Bounds(), at the same
+// coordinates, are written. DitherOrdered panics if size is not Bayer4x4 or
+// Bayer8x8.
+func DitherOrdered(dst *HorizontalNibble, src image.Image, size BayerSize) {
+	var m [][]int
+	switch size {
+	case Bayer4x4:
+		m = bayer4
+	case Bayer8x8:
+		m = bayer8
+	default:
+		panic("image4bit: invalid Bayer matrix size")
+	}
+	n := len(m)
+	steps := n * n
+
+	r := dst.Rect.Intersect(src.Bounds())
+	gray, _ := src.(*image.Gray)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := m[y&(n-1)]
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// level = floor(v * 15 / 255 + (index + 0.5) / steps)
+			v := luminance(src, gray, x, y)
+			level := (2*v*15*steps + (2*row[x&(n-1)]+1)*255) / (2 * 255 * steps)
+			dst.setNibble(x, y, uint8(min(level, 15)))
+		}
+	}
+}
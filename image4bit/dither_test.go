@@ -0,0 +1,177 @@
+package image4bit
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDitherFloydSteinbergMidGray(t *testing.T) {
+	r := image.Rect(0, 0, 16, 16)
+	src := image.NewUniform(color.Gray{Y: 128}) // Between levels 7 (119) and 8 (136)
+	img := NewHorizontalNibble(r)
+	DitherFloydSteinberg(img, src)
+
+	// Only the two nearest levels are used, in a fine mix rather than a
+	// flat fill
+	count := map[uint8]int{}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			count[img.Gray4At(x, y).Y]++
+		}
+	}
+	if len(count) != 2 || count[7] == 0 || count[8] == 0 {
+		t.Fatalf("levels used = %v, want a mix of 7 and 8", count)
+	}
+	if count[8] < 256*35/100 || count[8] > 256*65/100 {
+		t.Errorf("level 8 used for %d of 256 pixels, want about half", count[8])
+	}
+
+	// Neighbours mostly differ, like a checkerboard
+	changes := 0
+	for y := 0; y < 16; y++ {
+		for x := 1; x < 16; x++ {
+			if img.Gray4At(x, y) != img.Gray4At(x-1, y) {
+				changes++
+			}
+		}
+	}
+	if changes < 16*15/2 {
+		t.Errorf("%d horizontal level changes, want a checkerboard-like pattern", changes)
+	}
+
+	// The pattern is stable
+	again := NewHorizontalNibble(r)
+	DitherFloydSteinberg(again, src)
+	if !bytes.Equal(img.Pix, again.Pix) {
+		t.Error("dithering the same input twice gave different results")
+	}
+}
+
+func TestDitherFloydSteinbergExactLevels(t *testing.T) {
+	// Inputs that are exactly representable produce no error to diffuse,
+	// and a gray source matches the same colors through RGBA
+	gray := image.NewGray(image.Rect(0, 0, 4, 2))
+	rgba := image.NewRGBA(gray.Rect)
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i) * 0x22
+		rgba.Set(i%4, i/4, color.Gray{Y: gray.Pix[i]})
+	}
+	for _, src := range []image.Image{gray, rgba} {
+		img := NewHorizontalNibble(gray.Rect)
+		DitherFloydSteinberg(img, src)
+		if want := []byte{0x02, 0x46, 0x8A, 0xCE}; !bytes.Equal(img.Pix, want) {
+			t.Errorf("%T: Pix = %X, want %X", src, img.Pix, want)
+		}
+	}
+}
+
+func TestDitherFloydSteinbergRegion(t *testing.T) {
+	// Only the overlap of dst and src is written
+	img := NewHorizontalNibble(image.Rect(0, 0, 8, 2))
+	img.Fill(Gray4{Y: 3})
+	src := image.NewGray(image.Rect(2, 1, 5, 4))
+	for i := range src.Pix {
+		src.Pix[i] = 0xFF
+	}
+	DitherFloydSteinberg(img, src)
+	want := []byte{
+		0x33, 0x33, 0x33, 0x33,
+		0x33, 0xFF, 0xF3, 0x33,
+	}
+	if !bytes.Equal(img.Pix, want) {
+		t.Errorf("Pix = %X, want %X", img.Pix, want)
+	}
+}
+
+func TestBayerMatrices(t *testing.T) {
+	// Each matrix is a permutation of its threshold steps
+	for _, m := range [][][]int{bayer4, bayer8} {
+		seen := map[int]bool{}
+		for _, row := range m {
+			for _, v := range row {
+				seen[v] = true
+			}
+		}
+		n := len(m) * len(m)
+		for v := 0; v < n; v++ {
+			if !seen[v] {
+				t.Errorf("%d×%d matrix is missing %d", len(m), len(m), v)
+			}
+		}
+	}
+	if bayer8[0][1] != 32 || bayer8[1][1] != 16 {
+		t.Errorf("bayer8 starts %v %v, want [0 32 ...] [48 16 ...]", bayer8[0][:2], bayer8[1][:2])
+	}
+}
+
+func TestDitherOrderedTile(t *testing.T) {
+	// 123 is a quarter of the way from level 7 (119) to level 8 (136), so
+	// the 4 highest of the 16 thresholds round up
+	src := image.NewUniform(color.Gray{Y: 123})
+	img := NewHorizontalNibble(image.Rect(0, 0, 8, 8))
+	DitherOrdered(img, src, Bayer4x4)
+	tile := []byte{
+		0x77, 0x77,
+		0x87, 0x87,
+		0x77, 0x77,
+		0x87, 0x87,
+	}
+	for y := 0; y < 8; y++ {
+		row := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		want := tile[y%4*2 : y%4*2+2]
+		if !bytes.Equal(row, bytes.Repeat(want, 2)) {
+			t.Errorf("row %d = %X, want the tile row %X repeated", y, row, want)
+		}
+	}
+
+	// Exact levels are kept
+	for _, size := range []BayerSize{Bayer4x4, Bayer8x8} {
+		DitherOrdered(img, image.NewUniform(color.Gray{Y: 0x99}), size)
+		if want := bytes.Repeat([]byte{0x99}, len(img.Pix)); !bytes.Equal(img.Pix, want) {
+			t.Errorf("size %d: exact level gave %X", size, img.Pix)
+		}
+	}
+}
+
+func TestDitherOrderedDeterministic(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 29)
+	}
+	for _, size := range []BayerSize{Bayer4x4, Bayer8x8} {
+		a := NewHorizontalNibble(src.Rect)
+		b := NewHorizontalNibble(src.Rect)
+		b.Fill(Gray4{Y: 0xF})
+		DitherOrdered(a, src, size)
+		DitherOrdered(b, src, size)
+		if !bytes.Equal(a.Pix, b.Pix) {
+			t.Errorf("size %d: results differ: %X and %X", size, a.Pix, b.Pix)
+		}
+
+		// A pixel's result does not depend on its neighbours
+		src2 := image.NewRGBA(src.Rect)
+		copy(src2.Pix, src.Pix)
+		src2.Set(3, 3, color.White)
+		c := NewHorizontalNibble(src.Rect)
+		DitherOrdered(c, src2, size)
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 16; x++ {
+				if x == 3 && y == 3 {
+					continue
+				}
+				if a.Gray4At(x, y) != c.Gray4At(x, y) {
+					t.Errorf("size %d: changing (3, 3) changed (%d, %d)", size, x, y)
+				}
+			}
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("DitherOrdered with an invalid size should panic")
+		}
+	}()
+	DitherOrdered(NewHorizontalNibble(src.Rect), src, BayerSize(7))
+}
@@ -13,9 +13,38 @@
 //
 // This package provides:
 //
-// - Gray4: A color type representing 4-bit grayscale (0-15)
+// - Gray4: A color type representing 4-bit grayscale (0-15), with Equal, Less, Clamp and a lossless Gray16 conversion
+// - NewGray4: Builds a Gray4 from an int, clamping it to 0-15 instead of wrapping
 // - Gray4Model: A color model for converting standard Go colors to Gray4
-// - HorizontalNibble: An image.Image implementation optimized for SSD1322
+// - NewGray4Model: The same conversion with gamma encoding, to keep shadow detail
+// - NewPaletteModel: A color model picking the nearest entry of a custom 16-level palette
+// - HorizontalNibble: An image.Image implementation optimized for SSD1322 (with SubImage views)
+// - VerticalNibble: The same with two vertically adjacent pixels per byte, for rotated mountings
+// - Clone and CopyFrom: Deep copies of a HorizontalNibble, e.g. for undo snapshots
+// - DrawInto: A faster draw.Draw replacement for HorizontalNibble destinations
+// - Blit: Sprite copies between HorizontalNibble images, whole bytes at even offsets
+// - Fill and FillRect: Fast solid fills of a HorizontalNibble
+// - Invert: In-place inversion of the image data (15-v for every level)
+// - AdjustBrightness: In-place brightening or dimming with saturation
+// - FromGray and ToGray: Fast conversion from and to image.Gray images
+// - Threshold: Binarization into a black and white image.Gray
+// - FillFunc: Fills an image from a function of the pixel coordinates
+// - DrawLine and DrawRect: One pixel wide Bresenham lines and rectangle outlines
+// - DrawLineAA: Antialiased lines using the 16 gray levels
+// - DrawCircle, FillCircle and DrawArc: Midpoint circles, discs and arcs, e.g. for gauges
+// - DrawSparkline: Auto-scaled waveform plots of sample series
+// - DrawTextRotated: Bitmap text rendering at 0°, 90°, 180° or 270°
+// - GlyphCache and DrawCachedText: Text rendering from cached glyphs
+// - DrawText: Text in any font.Face at a baseline, anti-aliased by glyph coverage
+// - DitherFloydSteinberg: Error-diffusion dithering of any image into 16 levels
+// - DitherOrdered: Bayer matrix dithering, stable across animation frames
+// - FillGradient: A 16-step gray ramp for test patterns
+// - Histogram: Pixel counts per gray level, e.g. for automatic contrast
+// - AutoContrast: A contrast setting suggested by a frame's mean gray level
+// - EstimateRelativePower: A frame's panel current relative to all white, e.g. for battery budgeting
+// - ToLevels and FromLevels: Conversion to and from [][]uint8 level matrices
+// - EncodePNG and DecodePNG: Grayscale PNG storage of HorizontalNibble images
+// - EncodePGM: Binary PGM output, viewable without a PNG encoder
 //
 // Example usage:
 //
@@ -0,0 +1,296 @@
+package image4bit
+
+import (
+	"image"
+)
+
+// DrawInto copies the src pixels aligned at sp into the r region of dst,
+// like draw.Draw(dst, r, src, sp, draw.Src) but faster.
+//
+// *HorizontalNibble, *image.Gray and *image.Uniform sources are copied
+// directly without per-pixel color conversion (whole bytes at a time when
+// the nibbles line up). *image.YCbCr sources, such as camera or video
+// frames, are quantized straight from the Y (luma) plane, ignoring chroma;
+// this can differ by one level from Gray4Model, which converts to RGB
+// first. Any other source falls back to Gray4Model conversion of each
+// pixel. r is clipped to dst and to the source bounds.
+// Overlapping source and destination HorizontalNibble images are handled.
+func DrawInto(dst *HorizontalNibble, r image.Rectangle, src image.Image, sp image.Point) {
+	// Clip to dst and src bounds, keeping sp aligned with r.Min
+	orig := r.Min
+	r = r.Intersect(dst.Rect)
+	r = r.Intersect(src.Bounds().Add(orig.Sub(sp)))
+	if r.Empty() {
+		return
+	}
+	sp = sp.Add(r.Min.Sub(orig))
+
+	switch s := src.(type) {
+	case *image.Uniform:
+		dst.fill(r, Gray4Model.Convert(s.C).(Gray4).Y&0x0F)
+	case *HorizontalNibble:
+		dst.copyFrom(r, s, sp)
+	case *image.Gray:
+		dst.grayFrom(r, s, sp)
+	case *image.YCbCr:
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			i := s.YOffset(sp.X, sp.Y+y-r.Min.Y)
+			for x := r.Min.X; x < r.Max.X; x++ {
+				dst.setNibble(x, y, s.Y[i]>>4)
+				i++
+			}
+		}
+	default:
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			sy := sp.Y + y - r.Min.Y
+			for x := r.Min.X; x < r.Max.X; x++ {
+				c := Gray4Model.Convert(src.At(sp.X+x-r.Min.X, sy)).(Gray4)
+				dst.setNibble(x, y, c.Y&0x0F)
+			}
+		}
+	}
+}
+
+// Blit copies all of src into dst with the top-left corner of src at dp,
+// clipped to dst's bounds, for composing pre-rendered sprites. It is the
+// same as DrawInto with a HorizontalNibble source: when dp.X has the same
+// parity as src.Rect.Min.X (even, for a sprite starting at the origin) the
+// rows are copied as whole bytes, otherwise every pixel's nibble is moved
+// to the other half of its byte individually.
+func Blit(dst *HorizontalNibble, dp image.Point, src *HorizontalNibble) {
+	DrawInto(dst, src.Rect.Sub(src.Rect.Min).Add(dp), src, src.Rect.Min)
+}
+
+// FillFunc sets every pixel of p to fn(x, y), visiting the pixels of p.Rect
+// row by row. Adjacent pixels sharing a byte are packed and stored with a
+// single write when p.Rect starts on an even column.
+func FillFunc(p *HorizontalNibble, fn func(x, y int) Gray4) {
+	r := p.Rect
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		x := r.Min.X
+		if r.Min.X%2 == 0 {
+			i, _ := p.pixOffset(x, y)
+			for ; x+1 < r.Max.X; x += 2 {
+				hi, lo := fn(x, y).Y&0x0F, fn(x+1, y).Y&0x0F
+				p.Pix[i] = hi<<4 | lo
+				i++
+			}
+		}
+		for ; x < r.Max.X; x++ {
+			p.setNibble(x, y, fn(x, y).Y&0x0F)
+		}
+	}
+}
+
+// Fill sets every pixel of p to level. Whole bytes are stored at once, so
+// this is much faster than draw.Draw with an image.Uniform source. Only
+// the pixels within p.Rect are touched, so filling a SubImage leaves the
+// rest of its parent alone.
+func (p *HorizontalNibble) Fill(level Gray4) {
+	p.fill(p.Rect, level.Y&0x0F)
+}
+
+// FillRect sets every pixel of r, clipped to p's bounds, to level. Pixels
+// at odd-aligned edges are set one nibble at a time and the bytes between
+// them are filled whole.
+func (p *HorizontalNibble) FillRect(r image.Rectangle, level Gray4) {
+	r = r.Intersect(p.Rect)
+	if r.Empty() {
+		return
+	}
+	p.fill(r, level.Y&0x0F)
+}
+
+// DrawRect draws the one pixel wide outline of r onto p with c, inside r
+// like the edges FillRect would set. Each side is filled as a rectangle
+// clipped to p's bounds.
+func DrawRect(p *HorizontalNibble, r image.Rectangle, c Gray4) {
+	if r.Empty() {
+		return
+	}
+	p.FillRect(image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), c)
+	p.FillRect(image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), c)
+	p.FillRect(image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y), c)
+	p.FillRect(image.Rect(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y), c)
+}
+
+// nibble returns the 4-bit value at (x, y), which must be within p.Rect.
+func (p *HorizontalNibble) nibble(x, y int) uint8 {
+	offset, shift := p.pixOffset(x, y)
+	return (p.Pix[offset] >> shift) & 0x0F
+}
+
+// Invert replaces the level v of every pixel of p with 15-v, inverting the
+// image data itself rather than the display (see Dev.Invert in the ssd1322
+// package). Whole bytes are complemented at once, so 0xAB becomes 0x54.
+// Only the pixels within p.Rect are touched, so inverting a SubImage leaves
+// the rest of its parent, including the other pixels of its rows, alone.
+func (p *HorizontalNibble) Invert() {
+	r := p.Rect
+	if r.Empty() {
+		return
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		if p.Rect.Min.X%2 != 0 {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				p.invertNibble(x, y)
+			}
+			continue
+		}
+		x0, x1 := r.Min.X, r.Max.X
+		if x1%2 != 0 {
+			x1--
+			p.invertNibble(x1, y)
+		}
+		if x0 < x1 {
+			start, _ := p.pixOffset(x0, y)
+			row := p.Pix[start : start+(x1-x0)/2]
+			for i := range row {
+				row[i] ^= 0xFF
+			}
+		}
+	}
+}
+
+// AdjustBrightness adds delta to the level of every pixel of p, clamping
+// the result to [0, 15]: a negative delta dims the image and a positive one
+// brightens it. Unlike the display contrast, this changes the image data
+// only. Both pixels of a byte are mapped at once through a table, each
+// clamped independently; only the pixels within p.Rect are touched.
+func (p *HorizontalNibble) AdjustBrightness(delta int) {
+	r := p.Rect
+	if r.Empty() || delta == 0 {
+		return
+	}
+	var levels [16]uint8
+	for v := range levels {
+		levels[v] = uint8(max(0, min(15, v+delta)))
+	}
+	var bytes [256]uint8
+	for b := range bytes {
+		bytes[b] = levels[b>>4]<<4 | levels[b&0x0F]
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		if p.Rect.Min.X%2 != 0 {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				p.setNibble(x, y, levels[p.nibble(x, y)])
+			}
+			continue
+		}
+		x1 := r.Max.X
+		if x1%2 != 0 {
+			x1--
+			p.setNibble(x1, y, levels[p.nibble(x1, y)])
+		}
+		start, _ := p.pixOffset(r.Min.X, y)
+		row := p.Pix[start : start+(x1-r.Min.X)/2]
+		for i, b := range row {
+			row[i] = bytes[b]
+		}
+	}
+}
+
+// invertNibble replaces the 4-bit value v at (x, y), which must be within
+// p.Rect, with 15-v.
+func (p *HorizontalNibble) invertNibble(x, y int) {
+	offset, shift := p.pixOffset(x, y)
+	p.Pix[offset] ^= 0x0F << shift
+}
+
+// setNibble sets the 4-bit value v at (x, y), which must be within p.Rect.
+func (p *HorizontalNibble) setNibble(x, y int, v uint8) {
+	offset, shift := p.pixOffset(x, y)
+	p.Pix[offset] = (p.Pix[offset] &^ (0x0F << shift)) | (v << shift)
+}
+
+// fill sets every pixel of r, which must be within p.Rect, to the 4-bit
+// value v.
+func (p *HorizontalNibble) fill(r image.Rectangle, v uint8) {
+	if p.Rect.Min.X%2 != 0 {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				p.setNibble(x, y, v)
+			}
+		}
+		return
+	}
+
+	b := v<<4 | v
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		x0, x1 := r.Min.X, r.Max.X
+		if x0%2 != 0 {
+			p.setNibble(x0, y, v)
+			x0++
+		}
+		if x1%2 != 0 && x1 > x0 {
+			x1--
+			p.setNibble(x1, y, v)
+		}
+		if x0 < x1 {
+			start, _ := p.pixOffset(x0, y)
+			row := p.Pix[start : start+(x1-x0)/2]
+			for i := range row {
+				row[i] = b
+			}
+		}
+	}
+}
+
+// copyFrom copies the src pixels aligned at sp into r, which must be within
+// both p.Rect and the translated source bounds. Overlapping images are
+// handled by choosing the copy direction.
+func (p *HorizontalNibble) copyFrom(r image.Rectangle, src *HorizontalNibble, sp image.Point) {
+	// Copy rows bottom-up when moving content down within shared memory
+	y0, y1, dy := r.Min.Y, r.Max.Y, 1
+	if sp.Y < r.Min.Y {
+		y0, y1, dy = r.Max.Y-1, r.Min.Y-1, -1
+	}
+
+	// Whole bytes can be copied when both images use even-aligned rows and
+	// the source and destination pixels share the same nibble position
+	aligned := p.Rect.Min.X%2 == 0 && src.Rect.Min.X%2 == 0 && (r.Min.X-sp.X)%2 == 0
+
+	var line []uint8
+	for y := y0; y != y1; y += dy {
+		sy := sp.Y + y - r.Min.Y
+		if aligned {
+			// Read the unaligned edge pixels before writing anything so
+			// overlapping copies see the original values
+			x0, x1, sx0 := r.Min.X, r.Max.X, sp.X
+			var leadV, trailV uint8
+			lead := x0%2 != 0
+			if lead {
+				leadV = src.nibble(sx0, sy)
+				x0++
+				sx0++
+			}
+			trail := x1%2 != 0 && x1 > x0
+			if trail {
+				x1--
+				trailV = src.nibble(sx0+x1-x0, sy)
+			}
+			if x0 < x1 {
+				d, _ := p.pixOffset(x0, y)
+				s, _ := src.pixOffset(sx0, sy)
+				copy(p.Pix[d:d+(x1-x0)/2], src.Pix[s:s+(x1-x0)/2])
+			}
+			if lead {
+				p.setNibble(r.Min.X, y, leadV)
+			}
+			if trail {
+				p.setNibble(x1, y, trailV)
+			}
+			continue
+		}
+
+		// Buffer the source row so overlapping copies read original values
+		line = line[:0]
+		for x := r.Min.X; x < r.Max.X; x++ {
+			line = append(line, src.nibble(sp.X+x-r.Min.X, sy))
+		}
+		for i, v := range line {
+			p.setNibble(r.Min.X+i, y, v)
+		}
+	}
+}
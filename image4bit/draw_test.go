@@ -0,0 +1,402 @@
+package image4bit
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// patterned returns a HorizontalNibble of bounds r filled with a
+// non-repeating pattern.
+func patterned(r image.Rectangle) *HorizontalNibble {
+	img := NewHorizontalNibble(r)
+	for i := range img.Pix {
+		img.Pix[i] = byte(i*37 + 11)
+	}
+	return img
+}
+
+func TestDrawIntoMatchesDraw(t *testing.T) {
+	bounds := image.Rect(0, 0, 16, 6)
+
+	gray := image.NewGray(image.Rect(-3, -2, 20, 9))
+	for i := range gray.Pix {
+		gray.Pix[i] = byte(i * 13)
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, 16, 6))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = byte(i * 7)
+	}
+
+	sources := []struct {
+		name string
+		src  image.Image
+	}{
+		{"HorizontalNibble", patterned(image.Rect(0, 0, 16, 6))},
+		{"HorizontalNibble offset", patterned(image.Rect(2, 1, 12, 5))},
+		{"Gray", gray},
+		{"Uniform", image.NewUniform(color.Gray{Y: 0x9C})},
+		{"Uniform Gray4", image.NewUniform(Gray4{Y: 5})},
+		{"RGBA", rgba},
+	}
+	rects := []struct {
+		r  image.Rectangle
+		sp image.Point
+	}{
+		{image.Rect(0, 0, 16, 6), image.Point{}},
+		{image.Rect(1, 1, 9, 4), image.Pt(3, 2)},
+		{image.Rect(2, 0, 7, 6), image.Pt(1, 0)},
+		{image.Rect(3, 2, 4, 3), image.Pt(5, 1)},
+		{image.Rect(4, 2, 5, 3), image.Pt(4, 2)},
+		{image.Rect(-4, -1, 20, 8), image.Pt(-2, 0)},
+		{image.Rect(5, 1, 15, 5), image.Pt(100, 100)},
+	}
+
+	for _, s := range sources {
+		for _, rr := range rects {
+			want := patterned(bounds)
+			draw.Draw(want, rr.r, s.src, rr.sp, draw.Src)
+			got := patterned(bounds)
+			DrawInto(got, rr.r, s.src, rr.sp)
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Errorf("%s r=%v sp=%v: DrawInto = %X, want %X", s.name, rr.r, rr.sp, got.Pix, want.Pix)
+			}
+		}
+	}
+}
+
+func TestDrawIntoOverlapping(t *testing.T) {
+	moves := []image.Point{{2, 0}, {-2, 0}, {1, 0}, {-1, 0}, {0, 1}, {0, -1}, {3, 2}, {-3, -2}}
+	for _, m := range moves {
+		orig := patterned(image.Rect(0, 0, 16, 6))
+		want := NewHorizontalNibble(orig.Rect)
+		r := orig.Rect.Intersect(orig.Rect.Add(m))
+		copy(want.Pix, orig.Pix)
+		draw.Draw(want, r, patterned(orig.Rect), r.Min.Sub(m), draw.Src)
+
+		DrawInto(orig, r, orig, r.Min.Sub(m))
+		if !bytes.Equal(orig.Pix, want.Pix) {
+			t.Errorf("move %v: DrawInto = %X, want %X", m, orig.Pix, want.Pix)
+		}
+	}
+}
+
+func TestDrawIntoYCbCr(t *testing.T) {
+	src := image.NewYCbCr(image.Rect(0, 0, 16, 6), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = byte(i * 11)
+	}
+	// Strong chroma must not affect the result
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = 0x10, 0xF0
+	}
+
+	dst := patterned(image.Rect(0, 0, 16, 6))
+	DrawInto(dst, image.Rect(1, 1, 12, 5), src, image.Pt(2, 0))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 16; x++ {
+			want := patterned(dst.Rect).Gray4At(x, y).Y
+			if (image.Point{x, y}).In(image.Rect(1, 1, 12, 5)) {
+				want = src.Y[src.YOffset(x+1, y-1)] >> 4
+			}
+			if got := dst.Gray4At(x, y).Y; got != want {
+				t.Errorf("Gray4At(%d, %d) = %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestBlit(t *testing.T) {
+	src := patterned(image.Rect(0, 0, 6, 3))
+	offset := patterned(image.Rect(4, 2, 10, 5))
+
+	tests := []struct {
+		name string
+		src  *HorizontalNibble
+		dp   image.Point
+	}{
+		{"even", src, image.Pt(2, 1)},
+		{"odd", src, image.Pt(3, 1)},
+		{"clipped top left", src, image.Pt(-3, -1)},
+		{"clipped bottom right", src, image.Pt(9, 4)},
+		{"outside", src, image.Pt(12, 0)},
+		{"offset source", offset, image.Pt(1, 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := patterned(image.Rect(0, 0, 12, 6))
+			ref := patterned(image.Rect(0, 0, 12, 6))
+			Blit(dst, tt.dp, tt.src)
+			draw.Draw(ref, tt.src.Rect.Sub(tt.src.Rect.Min).Add(tt.dp), tt.src, tt.src.Rect.Min, draw.Src)
+			if !bytes.Equal(dst.Pix, ref.Pix) {
+				t.Errorf("Blit() Pix = %X, draw.Draw gives %X", dst.Pix, ref.Pix)
+			}
+		})
+	}
+}
+
+func BenchmarkBlit(b *testing.B) {
+	dst := NewHorizontalNibble(image.Rect(0, 0, 256, 64))
+	sprite := patterned(image.Rect(0, 0, 32, 16))
+	for _, dp := range []image.Point{{100, 20}, {101, 20}} {
+		name := "even"
+		if dp.X%2 != 0 {
+			name = "odd"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Blit(dst, dp, sprite)
+			}
+		})
+	}
+}
+
+func BenchmarkBlitDrawDraw(b *testing.B) {
+	dst := NewHorizontalNibble(image.Rect(0, 0, 256, 64))
+	sprite := patterned(image.Rect(0, 0, 32, 16))
+	r := sprite.Rect.Add(image.Pt(100, 20))
+	for i := 0; i < b.N; i++ {
+		draw.Draw(dst, r, sprite, image.Point{}, draw.Src)
+	}
+}
+
+func TestFillFunc(t *testing.T) {
+	gradient := func(x, y int) Gray4 { return Gray4{Y: uint8(x + y)} }
+
+	img := NewHorizontalNibble(image.Rect(0, 0, 8, 2))
+	FillFunc(img, gradient)
+	want := []byte{0x01, 0x23, 0x45, 0x67, 0x12, 0x34, 0x56, 0x78}
+	if !bytes.Equal(img.Pix, want) {
+		t.Errorf("FillFunc() Pix = %X, want %X", img.Pix, want)
+	}
+
+	// Odd-aligned bounds fall back to single pixels
+	img = NewHorizontalNibble(image.Rect(1, 0, 7, 1))
+	FillFunc(img, gradient)
+	for x := 1; x < 7; x++ {
+		if got := img.Gray4At(x, 0).Y; got != uint8(x) {
+			t.Errorf("odd bounds Gray4At(%d, 0) = %d, want %d", x, got, x)
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 6, 2))
+	img.Fill(Gray4{Y: 0x17})
+	if want := bytes.Repeat([]byte{0x77}, 6); !bytes.Equal(img.Pix, want) {
+		t.Errorf("Fill() Pix = %X, want %X", img.Pix, want)
+	}
+
+	// Filling a sub-image leaves the rest of the parent alone
+	parent := NewHorizontalNibble(image.Rect(0, 0, 8, 3))
+	parent.SubImage(image.Rect(2, 1, 6, 2)).Fill(Gray4{Y: 0xA})
+	want := []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0xAA, 0xAA, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	if !bytes.Equal(parent.Pix, want) {
+		t.Errorf("sub-image Fill() parent Pix = %X, want %X", parent.Pix, want)
+	}
+}
+
+func TestFillRect(t *testing.T) {
+	tests := []struct {
+		r    image.Rectangle
+		want []byte
+	}{
+		{image.Rect(1, 0, 6, 1), []byte{0x05, 0x55, 0x55, 0x00}},
+		{image.Rect(1, 0, 7, 1), []byte{0x05, 0x55, 0x55, 0x50}},
+		{image.Rect(2, 0, 4, 1), []byte{0x00, 0x55, 0x00, 0x00}},
+		{image.Rect(3, 0, 4, 1), []byte{0x00, 0x05, 0x00, 0x00}},
+		{image.Rect(-5, -1, 3, 1), []byte{0x55, 0x50, 0x00, 0x00}},
+		{image.Rect(9, 0, 12, 1), []byte{0x00, 0x00, 0x00, 0x00}},
+	}
+
+	for _, tt := range tests {
+		img := NewHorizontalNibble(image.Rect(0, 0, 8, 1))
+		img.FillRect(tt.r, Gray4{Y: 5})
+		if !bytes.Equal(img.Pix, tt.want) {
+			t.Errorf("FillRect(%v) Pix = %X, want %X", tt.r, img.Pix, tt.want)
+		}
+
+		// Same result as draw.Draw
+		ref := NewHorizontalNibble(image.Rect(0, 0, 8, 1))
+		draw.Draw(ref, tt.r, image.NewUniform(Gray4{Y: 5}), image.Point{}, draw.Src)
+		if !bytes.Equal(img.Pix, ref.Pix) {
+			t.Errorf("FillRect(%v) Pix = %X, draw.Draw gives %X", tt.r, img.Pix, ref.Pix)
+		}
+	}
+}
+
+func TestDrawRect(t *testing.T) {
+	tests := []struct {
+		r    image.Rectangle
+		want []byte
+	}{
+		{image.Rect(1, 0, 5, 3), []byte{
+			0x07, 0x77, 0x70,
+			0x07, 0x00, 0x70,
+			0x07, 0x77, 0x70,
+		}},
+		{image.Rect(2, 1, 4, 2), []byte{
+			0x00, 0x00, 0x00,
+			0x00, 0x77, 0x00,
+			0x00, 0x00, 0x00,
+		}},
+		// Sides outside the image are clipped away
+		{image.Rect(-1, 1, 3, 5), []byte{
+			0x00, 0x00, 0x00,
+			0x77, 0x70, 0x00,
+			0x00, 0x70, 0x00,
+		}},
+		{image.Rect(3, 1, 3, 2), make([]byte, 9)},
+	}
+
+	for _, tt := range tests {
+		img := NewHorizontalNibble(image.Rect(0, 0, 6, 3))
+		DrawRect(img, tt.r, Gray4{Y: 7})
+		if !bytes.Equal(img.Pix, tt.want) {
+			t.Errorf("DrawRect(%v) Pix = %X, want %X", tt.r, img.Pix, tt.want)
+		}
+	}
+}
+
+func TestInvert(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 4, 2))
+	copy(img.Pix, []byte{0xAB, 0x0F, 0x70, 0x12})
+	img.Invert()
+	if want := []byte{0x54, 0xF0, 0x8F, 0xED}; !bytes.Equal(img.Pix, want) {
+		t.Errorf("Invert() Pix = %X, want %X", img.Pix, want)
+	}
+
+	// Inverting a sub-image with an odd width leaves the rest of the
+	// parent alone, including the neighbouring rows and pixels
+	parent := NewHorizontalNibble(image.Rect(0, 0, 8, 3))
+	parent.SubImage(image.Rect(2, 1, 7, 2)).Invert()
+	want := []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0xFF, 0xFF, 0xF0,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	if !bytes.Equal(parent.Pix, want) {
+		t.Errorf("sub-image Invert() parent Pix = %X, want %X", parent.Pix, want)
+	}
+
+	// Images starting on an odd column are inverted pixel by pixel
+	odd := NewHorizontalNibble(image.Rect(1, 0, 5, 1))
+	odd.SetGray4(1, 0, Gray4{Y: 3})
+	odd.Invert()
+	for x, want := range []uint8{12, 15, 15, 15} {
+		if got := odd.Gray4At(x+1, 0).Y; got != want {
+			t.Errorf("odd-origin Gray4At(%d, 0) = %d, want %d", x+1, got, want)
+		}
+	}
+}
+
+func TestAdjustBrightness(t *testing.T) {
+	tests := []struct {
+		name  string
+		delta int
+		want  []byte
+	}{
+		{"brighten saturates", 5, []byte{0xF7, 0x5F}},
+		{"dim saturates", -5, []byte{0x90, 0x06}},
+		{"unchanged", 0, []byte{0xE2, 0x0B}},
+		{"beyond the range", 20, []byte{0xFF, 0xFF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Levels 14, 2, 0 and 11
+			img := NewHorizontalNibble(image.Rect(0, 0, 4, 1))
+			copy(img.Pix, []byte{0xE2, 0x0B})
+			img.AdjustBrightness(tt.delta)
+			if !bytes.Equal(img.Pix, tt.want) {
+				t.Errorf("AdjustBrightness(%d) Pix = %X, want %X", tt.delta, img.Pix, tt.want)
+			}
+		})
+	}
+
+	// Only the pixels of a sub-image change
+	parent := NewHorizontalNibble(image.Rect(0, 0, 8, 2))
+	parent.Fill(Gray4{Y: 7})
+	parent.SubImage(image.Rect(2, 1, 5, 2)).AdjustBrightness(2)
+	want := []byte{
+		0x77, 0x77, 0x77, 0x77,
+		0x77, 0x99, 0x97, 0x77,
+	}
+	if !bytes.Equal(parent.Pix, want) {
+		t.Errorf("sub-image AdjustBrightness() parent Pix = %X, want %X", parent.Pix, want)
+	}
+}
+
+func BenchmarkFill(b *testing.B) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 256, 64))
+	for i := 0; i < b.N; i++ {
+		img.Fill(Gray4{Y: 9})
+	}
+}
+
+func BenchmarkFillDrawUniform(b *testing.B) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 256, 64))
+	src := image.NewUniform(Gray4{Y: 9})
+	for i := 0; i < b.N; i++ {
+		draw.Draw(img, img.Rect, src, image.Point{}, draw.Src)
+	}
+}
+
+func benchmarkDraw(b *testing.B, src image.Image, fast bool) {
+	dst := NewHorizontalNibble(image.Rect(0, 0, 256, 64))
+	r := image.Rect(1, 0, 255, 64)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if fast {
+			DrawInto(dst, r, src, image.Point{})
+		} else {
+			draw.Draw(dst, r, src, image.Point{}, draw.Src)
+		}
+	}
+}
+
+func BenchmarkDrawIntoHorizontalNibble(b *testing.B) {
+	benchmarkDraw(b, patterned(image.Rect(0, 0, 256, 64)), true)
+}
+
+func BenchmarkDrawDrawHorizontalNibble(b *testing.B) {
+	benchmarkDraw(b, patterned(image.Rect(0, 0, 256, 64)), false)
+}
+
+func BenchmarkDrawIntoGray(b *testing.B) {
+	benchmarkDraw(b, image.NewGray(image.Rect(0, 0, 256, 64)), true)
+}
+
+func BenchmarkDrawDrawGray(b *testing.B) {
+	benchmarkDraw(b, image.NewGray(image.Rect(0, 0, 256, 64)), false)
+}
+
+func BenchmarkDrawIntoUniform(b *testing.B) {
+	benchmarkDraw(b, image.NewUniform(Gray4{Y: 9}), true)
+}
+
+func BenchmarkDrawDrawUniform(b *testing.B) {
+	benchmarkDraw(b, image.NewUniform(Gray4{Y: 9}), false)
+}
+
+func BenchmarkDrawIntoRGBA(b *testing.B) {
+	benchmarkDraw(b, image.NewRGBA(image.Rect(0, 0, 256, 64)), true)
+}
+
+func BenchmarkDrawDrawRGBA(b *testing.B) {
+	benchmarkDraw(b, image.NewRGBA(image.Rect(0, 0, 256, 64)), false)
+}
+
+func BenchmarkDrawIntoYCbCr(b *testing.B) {
+	benchmarkDraw(b, image.NewYCbCr(image.Rect(0, 0, 256, 64), image.YCbCrSubsampleRatio420), true)
+}
+
+func BenchmarkDrawDrawYCbCr(b *testing.B) {
+	benchmarkDraw(b, image.NewYCbCr(image.Rect(0, 0, 256, 64), image.YCbCrSubsampleRatio420), false)
+}
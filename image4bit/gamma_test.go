@@ -0,0 +1,61 @@
+package image4bit
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestGray4ModelGamma(t *testing.T) {
+	// Gamma 2.2 lifts every tone between black and white, most of all in
+	// the shadows
+	gamma := NewGray4Model(2.2)
+
+	tests := []struct {
+		name    string
+		in      color.Color
+		linear  uint8
+		gamma22 uint8
+	}{
+		{"black", color.Gray{Y: 0}, 0, 0},
+		{"deep shadow", color.Gray{Y: 16}, 1, 4},
+		{"shadow", color.Gray{Y: 48}, 3, 7},
+		{"mid-tone", color.Gray{Y: 128}, 8, 11},
+		{"highlight", color.Gray{Y: 200}, 12, 14},
+		{"white", color.Gray{Y: 255}, 15, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lin := Gray4Model.Convert(tt.in).(Gray4).Y
+			got := gamma.Convert(tt.in).(Gray4).Y
+			if lin != tt.linear {
+				t.Errorf("Gray4Model.Convert(%v) = %d, want %d", tt.in, lin, tt.linear)
+			}
+			if got != tt.gamma22 {
+				t.Errorf("NewGray4Model(2.2).Convert(%v) = %d, want %d", tt.in, got, tt.gamma22)
+			}
+		})
+	}
+}
+
+func TestGray4ModelLinear(t *testing.T) {
+	// A gamma of 1 matches the default model for every 8-bit gray
+	model := NewGray4Model(1)
+	for y := 0; y < 256; y++ {
+		c := color.Gray{Y: uint8(y)}
+		if got, want := model.Convert(c), Gray4Model.Convert(c); got != want {
+			t.Errorf("Convert(%d) = %v, want %v", y, got, want)
+		}
+	}
+	if got := model.Convert(Gray4{Y: 9}); got != (Gray4{Y: 9}) {
+		t.Errorf("Convert(Gray4{9}) = %v, want it unchanged", got)
+	}
+}
+
+func TestGray4ModelInvalidGamma(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewGray4Model(0) did not panic")
+		}
+	}()
+	NewGray4Model(0)
+}
@@ -0,0 +1,86 @@
+package image4bit
+
+import (
+	"image"
+)
+
+// FromGray converts src into dst, quantizing each 8-bit gray value to 4
+// bits with a shift (v >> 4), which matches Gray4Model for gray inputs.
+// Pixels are copied at the same coordinates, over the intersection of the
+// two images' bounds; the rest of dst is left alone.
+//
+// The source bytes are read directly, honouring both strides, and pairs of
+// pixels are packed into whole output bytes, so this is much faster than
+// draw.Draw for font rendering output or decoded grayscale images. DrawInto
+// uses the same conversion for *image.Gray sources.
+func FromGray(dst *HorizontalNibble, src *image.Gray) {
+	r := dst.Rect.Intersect(src.Rect)
+	if r.Empty() {
+		return
+	}
+	dst.grayFrom(r, src, r.Min)
+}
+
+// grayFrom copies the src pixels aligned at sp into r, which must be within
+// both p.Rect and the translated source bounds.
+func (p *HorizontalNibble) grayFrom(r image.Rectangle, src *image.Gray, sp image.Point) {
+	// Adjacent pixels share a byte only when the rows start on an even
+	// column
+	packed := p.Rect.Min.X%2 == 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		s := src.Pix[src.PixOffset(sp.X, sp.Y+y-r.Min.Y):]
+		x := r.Min.X
+		if packed {
+			if x%2 != 0 {
+				p.setNibble(x, y, s[0]>>4)
+				s = s[1:]
+				x++
+			}
+			i, _ := p.pixOffset(x, y)
+			for ; x+1 < r.Max.X; x += 2 {
+				p.Pix[i] = s[0]&0xF0 | s[1]>>4
+				s = s[2:]
+				i++
+			}
+		}
+		for ; x < r.Max.X; x++ {
+			p.setNibble(x, y, s[0]>>4)
+			s = s[1:]
+		}
+	}
+}
+
+// ToGray returns a copy of p as an *image.Gray with the same bounds, for use
+// with the standard library encoders and other image pipelines. Each level
+// is scaled to a byte (level * 17, so 15 becomes 255), which FromGray maps
+// back to the same level.
+func (p *HorizontalNibble) ToGray() *image.Gray {
+	gray := image.NewGray(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := gray.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x++ {
+			gray.Pix[i] = p.nibble(x, y) * 17
+			i++
+		}
+	}
+	return gray
+}
+
+// Threshold returns p binarized as an *image.Gray with the same bounds:
+// pixels whose level is at least level become white (255) and all others
+// black (0). A level of 0 makes every pixel white, and levels above 15 make
+// every pixel black. The result suits bi-level displays and crisp bitmap
+// rendering.
+func (p *HorizontalNibble) Threshold(level uint8) *image.Gray {
+	gray := image.NewGray(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		i := gray.PixOffset(p.Rect.Min.X, y)
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x++ {
+			if p.nibble(x, y) >= level {
+				gray.Pix[i] = 0xFF
+			}
+			i++
+		}
+	}
+	return gray
+}
@@ -0,0 +1,151 @@
+package image4bit
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestFromGray(t *testing.T) {
+	// Every 8-bit value, 16 per row
+	src := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	dst := NewHorizontalNibble(src.Rect)
+	FromGray(dst, src)
+
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			want := Gray4Model.Convert(src.GrayAt(x, y)).(Gray4)
+			if got := dst.Gray4At(x, y); got != want {
+				t.Errorf("Gray4At(%d, %d) for %d = %d, want %d", x, y, src.GrayAt(x, y).Y, got.Y, want.Y)
+			}
+		}
+	}
+}
+
+func TestFromGrayBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  image.Rectangle
+		src  image.Rectangle
+	}{
+		{"source offset and narrower", image.Rect(0, 0, 16, 4), image.Rect(3, 1, 12, 6)},
+		{"destination offset", image.Rect(4, 2, 20, 6), image.Rect(0, 0, 9, 5)},
+		{"odd destination origin", image.Rect(1, 0, 11, 3), image.Rect(0, 0, 8, 3)},
+		{"disjoint", image.Rect(0, 0, 8, 2), image.Rect(10, 10, 12, 12)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A sub-image of a wider gray image, so its stride exceeds its
+			// width
+			parent := image.NewGray(tt.src.Inset(-2))
+			for i := range parent.Pix {
+				parent.Pix[i] = uint8(i * 37)
+			}
+			src := parent.SubImage(tt.src).(*image.Gray)
+
+			dst := NewHorizontalNibble(tt.dst)
+			dst.Fill(Gray4{Y: 5})
+			FromGray(dst, src)
+
+			for y := tt.dst.Min.Y; y < tt.dst.Max.Y; y++ {
+				for x := tt.dst.Min.X; x < tt.dst.Max.X; x++ {
+					want := Gray4{Y: 5}
+					if (image.Point{X: x, Y: y}).In(tt.src) {
+						want = Gray4{Y: src.GrayAt(x, y).Y >> 4}
+					}
+					if got := dst.Gray4At(x, y); got != want {
+						t.Errorf("Gray4At(%d, %d) = %d, want %d", x, y, got.Y, want.Y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func benchmarkGray(b *testing.B, fast bool) {
+	src := image.NewGray(image.Rect(0, 0, 256, 64))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	dst := NewHorizontalNibble(src.Rect)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if fast {
+			FromGray(dst, src)
+		} else {
+			draw.Draw(dst, dst.Rect, src, image.Point{}, draw.Src)
+		}
+	}
+}
+
+func BenchmarkFromGray(b *testing.B) {
+	benchmarkGray(b, true)
+}
+
+func BenchmarkFromGrayDrawDraw(b *testing.B) {
+	benchmarkGray(b, false)
+}
+
+func TestToGray(t *testing.T) {
+	p := NewHorizontalNibble(image.Rect(2, 1, 18, 2))
+	for x := 2; x < 18; x++ {
+		p.SetGray4(x, 1, Gray4{Y: uint8(x - 2)})
+	}
+
+	gray := p.ToGray()
+	if gray.Rect != p.Rect {
+		t.Fatalf("ToGray().Rect = %v, want %v", gray.Rect, p.Rect)
+	}
+	for _, tt := range []struct{ level, want uint8 }{{0, 0}, {1, 17}, {8, 136}, {15, 255}} {
+		if got := gray.GrayAt(int(tt.level)+2, 1).Y; got != tt.want {
+			t.Errorf("level %d = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+
+	// FromGray restores every level
+	back := NewHorizontalNibble(p.Rect)
+	FromGray(back, gray)
+	for x := 2; x < 18; x++ {
+		if got, want := back.Gray4At(x, 1), p.Gray4At(x, 1); got != want {
+			t.Errorf("round trip Gray4At(%d, 1) = %d, want %d", x, got.Y, want.Y)
+		}
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	// One pixel per level
+	p := NewHorizontalNibble(image.Rect(0, 0, 16, 1))
+	for x := 0; x < 16; x++ {
+		p.SetGray4(x, 0, Gray4{Y: uint8(x)})
+	}
+
+	tests := []struct {
+		level uint8
+		white int // First level mapped to white
+	}{
+		{0, 0},
+		{1, 1},
+		{8, 8},
+		{15, 15},
+		{16, 16},
+	}
+	for _, tt := range tests {
+		got := p.Threshold(tt.level)
+		if got.Rect != p.Rect {
+			t.Fatalf("Threshold(%d).Rect = %v, want %v", tt.level, got.Rect, p.Rect)
+		}
+		for x := 0; x < 16; x++ {
+			want := uint8(0)
+			if x >= tt.white {
+				want = 0xFF
+			}
+			if v := got.GrayAt(x, 0).Y; v != want {
+				t.Errorf("Threshold(%d) at level %d = %d, want %d", tt.level, x, v, want)
+			}
+		}
+	}
+}
@@ -6,8 +6,10 @@
 package image4bit
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"slices"
 )
 
 // Gray4 represents a 4-bit grayscale color (0-15 intensity levels).
@@ -16,6 +18,22 @@ type Gray4 struct {
 	Y uint8
 }
 
+// NewGray4 returns the Gray4 for level, clamped to the range [0, 15].
+//
+// Values below 0 become black (0) and values above 15 become white (15).
+// This differs from constructing Gray4{Y: level} directly, where storage
+// keeps only the low 4 bits and out-of-range values wrap around
+// (e.g. 20 would be stored as 4).
+func NewGray4(level int) Gray4 {
+	if level < 0 {
+		return Gray4{Y: 0}
+	}
+	if level > 15 {
+		return Gray4{Y: 15}
+	}
+	return Gray4{Y: uint8(level)}
+}
+
 // RGBA converts the Gray4 color to standard RGBA.
 // The 4-bit gray value (0-15) is scaled to 16-bit (0-65535).
 func (c Gray4) RGBA() (r, g, b, a uint32) {
@@ -25,6 +43,36 @@ func (c Gray4) RGBA() (r, g, b, a uint32) {
 	return y, y, y, 0xFFFF
 }
 
+// Gray16 converts the Gray4 color to a 16-bit color.Gray16 without loss of
+// precision, using the same scaling as RGBA.
+func (c Gray4) Gray16() color.Gray16 {
+	return color.Gray16{Y: uint16(c.Y&0x0F) * 0x1111}
+}
+
+// Equal reports whether c and other are the same level, comparing only the
+// lower 4 bits of Y as the display does.
+func (c Gray4) Equal(other Gray4) bool {
+	return c.Y&0x0F == other.Y&0x0F
+}
+
+// Less reports whether c is darker than other, comparing only the lower 4
+// bits of Y.
+func (c Gray4) Less(other Gray4) bool {
+	return c.Y&0x0F < other.Y&0x0F
+}
+
+// Clamp returns the level of c limited to the range [lo, hi], with all
+// three compared on the lower 4 bits of Y. The result is always masked.
+func (c Gray4) Clamp(lo, hi Gray4) Gray4 {
+	if c.Less(lo) {
+		return Gray4{Y: lo.Y & 0x0F}
+	}
+	if hi.Less(c) {
+		return Gray4{Y: hi.Y & 0x0F}
+	}
+	return Gray4{Y: c.Y & 0x0F}
+}
+
 // toGray4 converts any color.Color to Gray4.
 func toGray4(c color.Color) color.Color {
 	if g, ok := c.(Gray4); ok {
@@ -116,6 +164,59 @@ func (p *HorizontalNibble) SetGray4(x, y int, c Gray4) {
 	p.Pix[offset] = (p.Pix[offset] &^ (0x0F << shift)) | ((c.Y & 0x0F) << shift)
 }
 
+// SubImage returns an image representing the portion of p visible through
+// r. The returned image shares pixels with p, so changes to either are
+// visible in the other.
+//
+// Since each byte holds two pixels, the portion must start on an even
+// column: SubImage panics if the intersection of r and p's bounds has an
+// odd Min.X. An odd Max.X is fine; the returned image then shares the byte
+// holding its last pixel with the pixel to its right.
+func (p *HorizontalNibble) SubImage(r image.Rectangle) *HorizontalNibble {
+	r = r.Intersect(p.Rect)
+	// An empty intersection may lie outside p.Rect, so it must not be
+	// used to index Pix
+	if r.Empty() {
+		return &HorizontalNibble{}
+	}
+	if r.Min.X%2 != 0 {
+		panic("image4bit: sub-image must start on an even column")
+	}
+	i, _ := p.pixOffset(r.Min.X, r.Min.Y)
+	return &HorizontalNibble{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Clone returns a deep copy of p with its own Pix of the same length and
+// the same Stride and Rect, for example to snapshot a frame before an
+// animation step. Unlike SubImage, changes to the copy are not visible in
+// p.
+func (p *HorizontalNibble) Clone() *HorizontalNibble {
+	return &HorizontalNibble{
+		Pix:    slices.Clone(p.Pix),
+		Stride: p.Stride,
+		Rect:   p.Rect,
+	}
+}
+
+// CopyFrom copies the pixels of src into p, e.g. to restore a snapshot
+// taken with Clone without allocating. The images must have the same size
+// but may have different origins or strides, such as a SubImage of a
+// larger image; otherwise CopyFrom returns an error and leaves p
+// unchanged.
+func (p *HorizontalNibble) CopyFrom(src *HorizontalNibble) error {
+	if p.Rect.Size() != src.Rect.Size() {
+		return fmt.Errorf("image4bit: cannot copy %v image into %v image", src.Rect.Size(), p.Rect.Size())
+	}
+	if !p.Rect.Empty() {
+		p.copyFrom(p.Rect, src, src.Rect.Min)
+	}
+	return nil
+}
+
 // pixOffset returns the byte offset and bit shift for the pixel at (x, y).
 // Memory layout: each byte contains 2 pixels horizontally.
 // High nibble (shift 4) = even x (left pixel)
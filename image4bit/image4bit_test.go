@@ -1,6 +1,7 @@
 package image4bit
 
 import (
+	"bytes"
 	"image"
 	"image/color"
 	"testing"
@@ -31,6 +32,98 @@ func TestGray4RGBA(t *testing.T) {
 	}
 }
 
+func TestNewGray4(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		want  uint8
+	}{
+		{"negative clamps to black", -3, 0},
+		{"zero", 0, 0},
+		{"in range", 7, 7},
+		{"white", 15, 15},
+		{"above range clamps to white", 20, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewGray4(tt.level); got.Y != tt.want {
+				t.Errorf("NewGray4(%d).Y = %d, want %d", tt.level, got.Y, tt.want)
+			}
+		})
+	}
+}
+
+func TestGray4Gray16(t *testing.T) {
+	tests := []struct {
+		name string
+		gray Gray4
+		want uint16
+	}{
+		{"black", Gray4{Y: 0}, 0x0000},
+		{"mid gray", Gray4{Y: 8}, 0x8888},
+		{"white", Gray4{Y: 15}, 0xFFFF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.gray.Gray16(); got.Y != tt.want {
+				t.Errorf("Gray16().Y = 0x%04X, want 0x%04X", got.Y, tt.want)
+			}
+		})
+	}
+}
+
+func TestGray4Compare(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Gray4
+		equal   bool
+		less    bool
+		greater bool
+	}{
+		{"same level", Gray4{Y: 5}, Gray4{Y: 5}, true, false, false},
+		{"high bits ignored", Gray4{Y: 0x05}, Gray4{Y: 0x15}, true, false, false},
+		{"darker", Gray4{Y: 3}, Gray4{Y: 12}, false, true, false},
+		{"lighter", Gray4{Y: 15}, Gray4{Y: 0}, false, false, true},
+		{"masked ordering", Gray4{Y: 0xF2}, Gray4{Y: 0x03}, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Equal(tt.b); got != tt.equal {
+				t.Errorf("%v.Equal(%v) = %v, want %v", tt.a, tt.b, got, tt.equal)
+			}
+			if got := tt.a.Less(tt.b); got != tt.less {
+				t.Errorf("%v.Less(%v) = %v, want %v", tt.a, tt.b, got, tt.less)
+			}
+			if got := tt.b.Less(tt.a); got != tt.greater {
+				t.Errorf("%v.Less(%v) = %v, want %v", tt.b, tt.a, got, tt.greater)
+			}
+		})
+	}
+}
+
+func TestGray4Clamp(t *testing.T) {
+	lo, hi := Gray4{Y: 4}, Gray4{Y: 10}
+	tests := []struct {
+		c    Gray4
+		want uint8
+	}{
+		{Gray4{Y: 0}, 4},
+		{Gray4{Y: 4}, 4},
+		{Gray4{Y: 7}, 7},
+		{Gray4{Y: 15}, 10},
+		{Gray4{Y: 0x17}, 7},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.Clamp(lo, hi); got.Y != tt.want {
+			t.Errorf("Gray4{Y: 0x%02X}.Clamp(4, 10).Y = %d, want %d", tt.c.Y, got.Y, tt.want)
+		}
+	}
+}
+
 func TestGray4ModelConvert(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -301,3 +394,106 @@ func TestHorizontalNibbleAllGrayLevels(t *testing.T) {
 		}
 	}
 }
+
+func TestHorizontalNibbleSubImage(t *testing.T) {
+	parent := NewHorizontalNibble(image.Rect(0, 0, 8, 4))
+	sub := parent.SubImage(image.Rect(2, 1, 7, 3))
+	if sub.Bounds() != image.Rect(2, 1, 7, 3) {
+		t.Errorf("Bounds() = %v, want %v", sub.Bounds(), image.Rect(2, 1, 7, 3))
+	}
+
+	// Writes through the sub-image land in the parent and vice versa
+	sub.SetGray4(2, 1, Gray4{Y: 0x5})
+	sub.SetGray4(6, 2, Gray4{Y: 0xC})
+	if got := parent.Gray4At(2, 1).Y; got != 0x5 {
+		t.Errorf("parent Gray4At(2, 1) = %d, want 5", got)
+	}
+	if got := parent.Gray4At(6, 2).Y; got != 0xC {
+		t.Errorf("parent Gray4At(6, 2) = %d, want 12", got)
+	}
+	parent.SetGray4(3, 2, Gray4{Y: 0x9})
+	if got := sub.Gray4At(3, 2).Y; got != 0x9 {
+		t.Errorf("sub Gray4At(3, 2) = %d, want 9", got)
+	}
+
+	// Pixels outside the sub-image are not reachable through it
+	sub.SetGray4(7, 2, Gray4{Y: 0xF})
+	sub.SetGray4(0, 0, Gray4{Y: 0xF})
+	if got := parent.Gray4At(7, 2).Y; got != 0 {
+		t.Errorf("parent Gray4At(7, 2) = %d, want 0", got)
+	}
+	if got := sub.Gray4At(1, 1); got != (Gray4{}) {
+		t.Errorf("sub Gray4At(1, 1) = %v, want Gray4{}", got)
+	}
+
+	// r is clipped to the parent, and an empty intersection is empty
+	if got := parent.SubImage(image.Rect(4, -2, 20, 2)).Bounds(); got != image.Rect(4, 0, 8, 2) {
+		t.Errorf("clipped Bounds() = %v, want %v", got, image.Rect(4, 0, 8, 2))
+	}
+	if got := parent.SubImage(image.Rect(10, 10, 12, 12)); !got.Bounds().Empty() || len(got.Pix) != 0 {
+		t.Errorf("disjoint SubImage = %v with %d bytes, want empty", got.Bounds(), len(got.Pix))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SubImage starting on an odd column should panic")
+		}
+	}()
+	parent.SubImage(image.Rect(1, 0, 4, 2))
+}
+
+func TestHorizontalNibbleClone(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(2, 1, 8, 4))
+	img.SetGray4(3, 2, Gray4{Y: 9})
+
+	clone := img.Clone()
+	if clone.Rect != img.Rect || clone.Stride != img.Stride || !bytes.Equal(clone.Pix, img.Pix) {
+		t.Fatalf("Clone() = %+v, want a copy of %+v", clone, img)
+	}
+
+	// Changes to either image are not visible in the other
+	clone.SetGray4(3, 2, Gray4{Y: 4})
+	img.SetGray4(6, 3, Gray4{Y: 15})
+	if got := img.Gray4At(3, 2).Y; got != 9 {
+		t.Errorf("original Gray4At(3, 2).Y = %d after changing the clone, want 9", got)
+	}
+	if got := clone.Gray4At(6, 3).Y; got != 0 {
+		t.Errorf("clone Gray4At(6, 3).Y = %d after changing the original, want 0", got)
+	}
+}
+
+func TestHorizontalNibbleCopyFrom(t *testing.T) {
+	src := NewHorizontalNibble(image.Rect(0, 0, 6, 2))
+	for i := range src.Pix {
+		src.Pix[i] = byte(0x12 * (i + 1))
+	}
+
+	// Same size, different origin and stride
+	parent := NewHorizontalNibble(image.Rect(0, 0, 10, 4))
+	dst := parent.SubImage(image.Rect(4, 1, 10, 3))
+	if err := dst.CopyFrom(src); err != nil {
+		t.Fatalf("CopyFrom() error = %v", err)
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 6; x++ {
+			if got, want := dst.Gray4At(4+x, 1+y), src.Gray4At(x, y); got != want {
+				t.Errorf("Gray4At(%d, %d) = %v, want %v", 4+x, 1+y, got, want)
+			}
+		}
+	}
+	if got := parent.Gray4At(3, 1).Y; got != 0 {
+		t.Errorf("pixel outside the sub-image = %d, want 0", got)
+	}
+
+	// Size mismatches are rejected without touching the destination
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 4, 2), image.Rect(0, 0, 6, 3)} {
+		other := NewHorizontalNibble(r)
+		other.Fill(Gray4{Y: 5})
+		if err := other.CopyFrom(src); err == nil {
+			t.Errorf("CopyFrom() into %v succeeded, want error", r)
+		}
+		if got := other.Gray4At(0, 0).Y; got != 5 {
+			t.Errorf("CopyFrom() into %v changed the destination", r)
+		}
+	}
+}
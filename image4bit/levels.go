@@ -0,0 +1,48 @@
+package image4bit
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ToLevels returns the pixels of p as a row-major matrix of gray levels
+// (0-15), indexed as levels[y][x] relative to p.Rect.Min.
+func ToLevels(p *HorizontalNibble) [][]uint8 {
+	r := p.Rect
+	levels := make([][]uint8, r.Dy())
+	for y := range levels {
+		row := make([]uint8, r.Dx())
+		for x := range row {
+			row[x] = p.nibble(r.Min.X+x, r.Min.Y+y)
+		}
+		levels[y] = row
+	}
+	return levels
+}
+
+// FromLevels returns a HorizontalNibble at the origin holding a row-major
+// matrix of gray levels, indexed as levels[y][x]. Every row must have the
+// same, even length and every value must be at most 15.
+func FromLevels(levels [][]uint8) (*HorizontalNibble, error) {
+	w := 0
+	if len(levels) > 0 {
+		w = len(levels[0])
+	}
+	if w%2 != 0 {
+		return nil, errors.New("image4bit: width must be even")
+	}
+	p := NewHorizontalNibble(image.Rect(0, 0, w, len(levels)))
+	for y, row := range levels {
+		if len(row) != w {
+			return nil, fmt.Errorf("image4bit: row %d has %d levels, want %d", y, len(row), w)
+		}
+		for x, v := range row {
+			if v > 15 {
+				return nil, fmt.Errorf("image4bit: level %d at (%d, %d) out of range", v, x, y)
+			}
+			p.setNibble(x, y, v)
+		}
+	}
+	return p, nil
+}
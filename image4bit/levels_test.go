@@ -0,0 +1,54 @@
+package image4bit
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestLevelsRoundTrip(t *testing.T) {
+	levels := [][]uint8{
+		{0, 1, 2, 3},
+		{15, 14, 13, 12},
+		{7, 8, 9, 10},
+	}
+	p, err := FromLevels(levels)
+	if err != nil {
+		t.Fatalf("FromLevels() error = %v", err)
+	}
+	if want := image.Rect(0, 0, 4, 3); p.Rect != want {
+		t.Errorf("FromLevels() bounds = %v, want %v", p.Rect, want)
+	}
+	if want := []byte{0x01, 0x23, 0xFE, 0xDC, 0x78, 0x9A}; !bytes.Equal(p.Pix, want) {
+		t.Errorf("FromLevels() Pix = %X, want %X", p.Pix, want)
+	}
+	if got := ToLevels(p); !reflect.DeepEqual(got, levels) {
+		t.Errorf("ToLevels() = %v, want %v", got, levels)
+	}
+
+	// ToLevels is relative to the image origin
+	sub := NewHorizontalNibble(image.Rect(2, 1, 4, 2))
+	sub.SetGray4(3, 1, Gray4{Y: 9})
+	if got, want := ToLevels(sub), [][]uint8{{0, 9}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ToLevels(offset) = %v, want %v", got, want)
+	}
+}
+
+func TestFromLevelsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels [][]uint8
+	}{
+		{"ragged", [][]uint8{{1, 2}, {3, 4, 5, 6}}},
+		{"odd width", [][]uint8{{1, 2, 3}}},
+		{"out of range", [][]uint8{{1, 16}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromLevels(tt.levels); err == nil {
+				t.Error("FromLevels() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package image4bit
+
+import (
+	"image"
+	"math"
+)
+
+// DrawLineAA draws an antialiased line from (x0, y0) to (x1, y1) onto p with
+// color c, using Xiaolin Wu's algorithm.
+//
+// Each step along the major axis lights the two pixels straddling the ideal
+// line, blending c into the existing pixel in proportion to its coverage:
+// a pixel half covered by the line ends halfway between its old level and
+// c. The endpoints are drawn with half coverage, so lines sharing an
+// endpoint join smoothly. Pixels outside p's bounds are clipped.
+func DrawLineAA(p *HorizontalNibble, x0, y0, x1, y1 int, c Gray4) {
+	target := float64(c.Y & 0x0F)
+	plot := func(x, y int, coverage float64, steep bool) {
+		if steep {
+			x, y = y, x
+		}
+		if coverage <= 0 || !(x >= p.Rect.Min.X && x < p.Rect.Max.X && y >= p.Rect.Min.Y && y < p.Rect.Max.Y) {
+			return
+		}
+		old := float64(p.nibble(x, y))
+		p.setNibble(x, y, uint8(math.Round(old+(target-old)*coverage)))
+	}
+
+	// Walk along the major axis, left to right
+	steep := abs(y1-y0) > abs(x1-x0)
+	if steep {
+		x0, y0, x1, y1 = y0, x0, y1, x1
+	}
+	if x0 > x1 {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	gradient := 1.0
+	if dx := x1 - x0; dx != 0 {
+		gradient = float64(y1-y0) / float64(dx)
+	}
+
+	// Endpoints lie on pixel centers, so they get half coverage
+	plot(x0, y0, 0.5, steep)
+	plot(x1, y1, 0.5, steep)
+
+	y := float64(y0) + gradient
+	for x := x0 + 1; x < x1; x++ {
+		base := math.Floor(y)
+		frac := y - base
+		plot(x, int(base), 1-frac, steep)
+		plot(x, int(base)+1, frac, steep)
+		y += gradient
+	}
+}
+
+// DrawLine draws a one pixel wide line from (x0, y0) to (x1, y1) onto p
+// with color c, using Bresenham's algorithm; both endpoints are included.
+// Horizontal and vertical lines are filled as rectangles. Pixels outside
+// p's bounds are clipped. Use DrawLineAA for antialiased edges.
+func DrawLine(p *HorizontalNibble, x0, y0, x1, y1 int, c Gray4) {
+	if x0 == x1 || y0 == y1 {
+		p.FillRect(image.Rect(min(x0, x1), min(y0, y1), max(x0, x1)+1, max(y0, y1)+1), c)
+		return
+	}
+	v := c.Y & 0x0F
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	for e := dx + dy; ; {
+		if (image.Point{x0, y0}).In(p.Rect) {
+			p.setNibble(x0, y0, v)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// abs returns the absolute value of v.
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
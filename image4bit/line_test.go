@@ -0,0 +1,124 @@
+package image4bit
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestDrawLineAADiagonal(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 8, 8))
+	DrawLineAA(img, 0, 0, 5, 5, Gray4{Y: 15})
+
+	// A 45° line covers the interior diagonal pixels fully and its ends
+	// half
+	for i := 1; i < 5; i++ {
+		if got := img.Gray4At(i, i).Y; got != 15 {
+			t.Errorf("Gray4At(%d, %d) = %d, want 15", i, i, got)
+		}
+	}
+	for _, i := range []int{0, 5} {
+		if got := img.Gray4At(i, i).Y; got != 8 {
+			t.Errorf("endpoint Gray4At(%d, %d) = %d, want 8", i, i, got)
+		}
+	}
+	if got := img.Gray4At(2, 1).Y; got != 0 {
+		t.Errorf("off-line Gray4At(2, 1) = %d, want 0", got)
+	}
+}
+
+func TestDrawLineAAShallow(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 12, 4))
+	DrawLineAA(img, 0, 0, 9, 3, Gray4{Y: 15})
+
+	// Every interior column splits the intensity between two rows, with
+	// intermediate levels wherever the line passes between pixel centers
+	intermediate := 0
+	for x := 1; x < 9; x++ {
+		sum := 0
+		for y := 0; y < 4; y++ {
+			v := int(img.Gray4At(x, y).Y)
+			sum += v
+			if v > 0 && v < 15 {
+				intermediate++
+			}
+		}
+		if sum < 14 || sum > 16 {
+			t.Errorf("column %d intensity = %d, want about 15", x, sum)
+		}
+	}
+	if intermediate == 0 {
+		t.Error("shallow line has no intermediate gray pixels")
+	}
+	if got := img.Gray4At(1, 0).Y; got <= 7 || got >= 15 {
+		t.Errorf("Gray4At(1, 0) = %d, want mostly covered", got)
+	}
+}
+
+func TestDrawLineAAClips(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 4, 4))
+	// Must not panic when the line extends past the image
+	DrawLineAA(img, -10, -3, 20, 7, Gray4{Y: 15})
+	DrawLineAA(img, 2, -5, 2, 9, Gray4{Y: 15})
+}
+
+// litPixels returns the pixels of p with a non-zero level, row by row.
+func litPixels(p *HorizontalNibble) []image.Point {
+	var lit []image.Point
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x++ {
+			if p.Gray4At(x, y).Y != 0 {
+				lit = append(lit, image.Pt(x, y))
+			}
+		}
+	}
+	return lit
+}
+
+func TestDrawLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 int
+		want           []image.Point
+	}{
+		{"horizontal", 1, 2, 4, 2, []image.Point{{1, 2}, {2, 2}, {3, 2}, {4, 2}}},
+		{"horizontal reversed", 4, 2, 1, 2, []image.Point{{1, 2}, {2, 2}, {3, 2}, {4, 2}}},
+		{"vertical", 3, 4, 3, 1, []image.Point{{3, 1}, {3, 2}, {3, 3}, {3, 4}}},
+		{"45 degrees", 0, 0, 3, 3, []image.Point{{0, 0}, {1, 1}, {2, 2}, {3, 3}}},
+		{"-45 degrees", 1, 4, 4, 1, []image.Point{{4, 1}, {3, 2}, {2, 3}, {1, 4}}},
+		{"shallow", 0, 0, 5, 2, []image.Point{{0, 0}, {1, 0}, {2, 1}, {3, 1}, {4, 2}, {5, 2}}},
+		{"single pixel", 2, 2, 2, 2, []image.Point{{2, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := NewHorizontalNibble(image.Rect(0, 0, 6, 6))
+			DrawLine(img, tt.x0, tt.y0, tt.x1, tt.y1, Gray4{Y: 9})
+			if got := litPixels(img); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lit pixels = %v, want %v", got, tt.want)
+			}
+			if got := img.Gray4At(tt.x0, tt.y0).Y; got != 9 {
+				t.Errorf("endpoint level = %d, want 9", got)
+			}
+		})
+	}
+}
+
+func TestDrawLineClips(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 4, 4))
+
+	// A diagonal through the image from far outside keeps its inside part
+	DrawLine(img, -3, -3, 8, 8, Gray4{Y: 15})
+	want := []image.Point{{0, 0}, {1, 1}, {2, 2}, {3, 3}}
+	if got := litPixels(img); !reflect.DeepEqual(got, want) {
+		t.Errorf("lit pixels = %v, want %v", got, want)
+	}
+
+	// Lines entirely outside draw nothing
+	img.Fill(Gray4{})
+	DrawLine(img, -5, 1, -1, 3, Gray4{Y: 15})
+	DrawLine(img, 0, 9, 3, 9, Gray4{Y: 15})
+	DrawLine(img, 7, -2, 7, 6, Gray4{Y: 15})
+	if got := litPixels(img); len(got) != 0 {
+		t.Errorf("lit pixels = %v, want none", got)
+	}
+}
@@ -0,0 +1,32 @@
+package image4bit
+
+import (
+	"image/color"
+)
+
+// NewPaletteModel returns a color model that maps each color to the index of
+// the nearest entry of a 16-level gray palette.
+//
+// levels holds the 8-bit gray value (0-255) that each index represents, for
+// example the output of a custom grayscale table loaded on the controller.
+// Convert computes the luminance of the input with the same weights as
+// Gray4Model and returns a Gray4 whose Y is the index of the closest level;
+// ties go to the lowest index. The levels need not be sorted.
+func NewPaletteModel(levels [16]uint8) color.Model {
+	return color.ModelFunc(func(c color.Color) color.Color {
+		r, g, b, _ := c.RGBA()
+		y := int((299*r + 587*g + 114*b + 500) / 1000 >> 8)
+
+		best, bestDist := 0, 256
+		for i, l := range levels {
+			dist := y - int(l)
+			if dist < 0 {
+				dist = -dist
+			}
+			if dist < bestDist {
+				best, bestDist = i, dist
+			}
+		}
+		return Gray4{Y: uint8(best)}
+	})
+}
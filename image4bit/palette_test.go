@@ -0,0 +1,34 @@
+package image4bit
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPaletteModel(t *testing.T) {
+	// Gamma-like palette, denser near black
+	levels := [16]uint8{0, 2, 5, 9, 14, 20, 28, 38, 50, 65, 83, 104, 128, 160, 200, 255}
+	model := NewPaletteModel(levels)
+
+	tests := []struct {
+		name string
+		in   color.Color
+		want uint8
+	}{
+		{"black", color.Gray{Y: 0}, 0},
+		{"exact level", color.Gray{Y: 50}, 8},
+		{"nearer lower", color.Gray{Y: 140}, 12},
+		{"nearer upper", color.Gray{Y: 150}, 13},
+		{"tie goes to lower index", color.Gray{Y: 144}, 12},
+		{"white", color.Gray{Y: 255}, 15},
+		{"rgb luminance", color.RGBA{R: 255, A: 255}, 10}, // Y = 76
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := model.Convert(tt.in).(Gray4)
+			if got.Y != tt.want {
+				t.Errorf("Convert(%v) = %d, want %d", tt.in, got.Y, tt.want)
+			}
+		})
+	}
+}
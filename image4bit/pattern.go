@@ -0,0 +1,30 @@
+package image4bit
+
+import (
+	"image"
+)
+
+// FillGradient fills the r region of p with a 16-step gray ramp from black
+// (0) to white (15), left to right if horizontal is true and top to bottom
+// otherwise. Each level gets an equal share of the width or height, so a
+// 256-pixel wide ramp has 16-pixel bars. r is clipped to p's bounds before
+// the steps are computed.
+func FillGradient(p *HorizontalNibble, r image.Rectangle, horizontal bool) {
+	r = r.Intersect(p.Rect)
+	if r.Empty() {
+		return
+	}
+	n := r.Dy()
+	if horizontal {
+		n = r.Dx()
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := y - r.Min.Y
+			if horizontal {
+				i = x - r.Min.X
+			}
+			p.setNibble(x, y, uint8(i*16/n))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package image4bit
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFillGradient(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 32, 16))
+	FillGradient(img, img.Rect, true)
+	for x := 0; x < 32; x++ {
+		if got, want := img.Gray4At(x, 5).Y, uint8(x/2); got != want {
+			t.Errorf("horizontal Gray4At(%d, 5) = %d, want %d", x, got, want)
+		}
+	}
+
+	FillGradient(img, img.Rect, false)
+	for y := 0; y < 16; y++ {
+		if got, want := img.Gray4At(7, y).Y, uint8(y); got != want {
+			t.Errorf("vertical Gray4At(7, %d) = %d, want %d", y, got, want)
+		}
+	}
+
+	// Only r is touched, and the ramp spans r rather than the image
+	img = NewHorizontalNibble(image.Rect(0, 0, 40, 2))
+	FillGradient(img, image.Rect(4, 0, 36, 1), true)
+	if got := img.Gray4At(35, 0).Y; got != 15 {
+		t.Errorf("Gray4At(35, 0) = %d, want 15", got)
+	}
+	if got := img.Gray4At(36, 0).Y; got != 0 {
+		t.Errorf("Gray4At(36, 0) = %d, want 0", got)
+	}
+	if got := img.Gray4At(35, 1).Y; got != 0 {
+		t.Errorf("Gray4At(35, 1) = %d, want 0", got)
+	}
+}
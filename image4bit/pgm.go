@@ -0,0 +1,28 @@
+package image4bit
+
+import (
+	"fmt"
+	"io"
+)
+
+// EncodePGM writes p to w as a binary (P5) portable graymap, a simple format
+// that most image viewers can open.
+//
+// Each 4-bit value is scaled to a byte (value * 0x11) with a maxval of 255.
+// Only the pixels within p.Rect are written, one row at a time.
+func EncodePGM(w io.Writer, p *HorizontalNibble) error {
+	r := p.Rect
+	if _, err := fmt.Fprintf(w, "P5\n%d %d\n255\n", r.Dx(), r.Dy()); err != nil {
+		return err
+	}
+	row := make([]byte, r.Dx())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			row[x-r.Min.X] = p.nibble(x, y) * 0x11
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
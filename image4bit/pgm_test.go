@@ -0,0 +1,44 @@
+package image4bit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"testing"
+)
+
+func TestEncodePGM(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(10, 20, 14, 22))
+	img.SetGray4(10, 20, Gray4{Y: 15})
+	img.SetGray4(11, 20, Gray4{Y: 1})
+	img.SetGray4(13, 21, Gray4{Y: 8})
+
+	var buf bytes.Buffer
+	if err := EncodePGM(&buf, img); err != nil {
+		t.Fatalf("EncodePGM() error = %v", err)
+	}
+
+	r := bufio.NewReader(&buf)
+	var magic string
+	var w, h, maxval int
+	if _, err := fmt.Fscanf(r, "%s\n%d %d\n%d\n", &magic, &w, &h, &maxval); err != nil {
+		t.Fatalf("parsing header: %v", err)
+	}
+	if magic != "P5" || w != 4 || h != 2 || maxval != 255 {
+		t.Errorf("header = %s %dx%d max %d, want P5 4x2 max 255", magic, w, h, maxval)
+	}
+
+	pix, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0xFF, 0x11, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x88,
+	}
+	if !bytes.Equal(pix, want) {
+		t.Errorf("pixels = %X, want %X", pix, want)
+	}
+}
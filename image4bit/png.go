@@ -0,0 +1,35 @@
+package image4bit
+
+import (
+	"errors"
+	"image/png"
+	"io"
+)
+
+// EncodePNG writes p to w as an 8-bit grayscale PNG.
+//
+// Each 4-bit value is scaled to a byte as by ToGray, so DecodePNG restores
+// it exactly. PNG has no image origin, so only the size of p.Rect is kept.
+func EncodePNG(w io.Writer, p *HorizontalNibble) error {
+	return png.Encode(w, p.ToGray())
+}
+
+// DecodePNG reads a PNG from r and quantizes it to 4-bit gray, keeping the
+// top 4 bits of each 8-bit gray value (color images are converted with
+// Gray4Model, see DrawInto). The result has the decoded image's bounds.
+//
+// Since HorizontalNibble packs two pixels per byte, images with an odd
+// width are rejected with an error.
+func DecodePNG(r io.Reader) (*HorizontalNibble, error) {
+	src, err := png.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	if b.Dx()%2 != 0 {
+		return nil, errors.New("image4bit: PNG width must be even")
+	}
+	p := NewHorizontalNibble(b)
+	DrawInto(p, b, src, b.Min)
+	return p, nil
+}
@@ -0,0 +1,95 @@
+package image4bit
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestPNGRoundTrip(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 16, 2))
+	for x := 0; x < 16; x++ {
+		img.SetGray4(x, 0, Gray4{Y: uint8(x)})
+		img.SetGray4(x, 1, Gray4{Y: uint8(15 - x)})
+	}
+
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, img); err != nil {
+		t.Fatalf("EncodePNG() error = %v", err)
+	}
+
+	// The file is a standard grayscale PNG
+	std, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	if _, ok := std.(*image.Gray); !ok {
+		t.Errorf("decoded %T, want *image.Gray", std)
+	}
+	if got := std.At(5, 0).(color.Gray).Y; got != 0x55 {
+		t.Errorf("level 5 encoded as %d, want 0x55", got)
+	}
+
+	got, err := DecodePNG(&buf)
+	if err != nil {
+		t.Fatalf("DecodePNG() error = %v", err)
+	}
+	if got.Bounds() != img.Bounds() {
+		t.Errorf("Bounds() = %v, want %v", got.Bounds(), img.Bounds())
+	}
+	if !bytes.Equal(got.Pix, img.Pix) {
+		t.Errorf("round trip Pix = %X, want %X", got.Pix, img.Pix)
+	}
+}
+
+func TestPNGSize(t *testing.T) {
+	// Only the size of an offset image survives
+	img := NewHorizontalNibble(image.Rect(4, 2, 10, 5))
+	img.SetGray4(4, 2, Gray4{Y: 9})
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodePNG(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != image.Rect(0, 0, 6, 3) {
+		t.Errorf("Bounds() = %v, want %v", got.Bounds(), image.Rect(0, 0, 6, 3))
+	}
+	if got.Gray4At(0, 0).Y != 9 {
+		t.Errorf("Gray4At(0, 0) = %d, want 9", got.Gray4At(0, 0).Y)
+	}
+}
+
+func TestDecodePNGErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 5, 2))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodePNG(&buf); err == nil {
+		t.Error("DecodePNG() of an odd-width image should fail")
+	}
+	if _, err := DecodePNG(bytes.NewReader([]byte("not a png"))); err == nil {
+		t.Error("DecodePNG() of invalid data should fail")
+	}
+}
+
+func TestDecodePNGColor(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.White)
+	src.Set(1, 0, color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodePNG(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Pix[0] != 0xF8 {
+		t.Errorf("Pix[0] = %02X, want F8", got.Pix[0])
+	}
+}
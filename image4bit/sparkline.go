@@ -0,0 +1,67 @@
+package image4bit
+
+import (
+	"image"
+	"math"
+)
+
+// DrawSparkline plots samples as a connected line across r with color c,
+// auto-scaled so the smallest sample lands on the bottom row of r and the
+// largest on the top row. Samples that are all equal are drawn across the
+// middle row.
+//
+// Each column of r gets one value: the samples are spread evenly from the
+// left edge to the right edge and linearly interpolated between, so fewer
+// samples than columns are stretched and more are subsampled. Each column is
+// filled from the previous column's row to its own, so steep changes stay
+// connected. Pixels outside p's bounds are clipped.
+func DrawSparkline(p *HorizontalNibble, r image.Rectangle, samples []float64, c Gray4) {
+	if r.Empty() || len(samples) == 0 {
+		return
+	}
+	lo, hi := samples[0], samples[0]
+	for _, v := range samples[1:] {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+
+	// row returns the row of r for value v
+	h := r.Dy()
+	row := func(v float64) int {
+		if hi == lo {
+			return r.Min.Y + (h-1)/2
+		}
+		return r.Max.Y - 1 - int(math.Round((v-lo)/(hi-lo)*float64(h-1)))
+	}
+
+	// value returns the interpolated sample for column i of w
+	w := r.Dx()
+	value := func(i int) float64 {
+		if len(samples) == 1 || w == 1 {
+			return samples[0]
+		}
+		pos := float64(i) * float64(len(samples)-1) / float64(w-1)
+		j := int(pos)
+		if j >= len(samples)-1 {
+			return samples[len(samples)-1]
+		}
+		frac := pos - float64(j)
+		return samples[j] + (samples[j+1]-samples[j])*frac
+	}
+
+	clip := r.Intersect(p.Rect)
+	level := c.Y & 0x0F
+	prev := row(value(0))
+	for i := 0; i < w; i++ {
+		x := r.Min.X + i
+		y := row(value(i))
+		// Join with the previous column's row
+		y0, y1 := min(prev, y), max(prev, y)
+		prev = y
+		if x < clip.Min.X || x >= clip.Max.X {
+			continue
+		}
+		for py := max(y0, clip.Min.Y); py <= min(y1, clip.Max.Y-1); py++ {
+			p.setNibble(x, py, level)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package image4bit
+
+import (
+	"image"
+	"testing"
+)
+
+// plottedRows returns the topmost lit row of each column of r, or -1 for an
+// empty column.
+func plottedRows(p *HorizontalNibble, r image.Rectangle) []int {
+	rows := make([]int, 0, r.Dx())
+	for x := r.Min.X; x < r.Max.X; x++ {
+		top := -1
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			if p.Gray4At(x, y).Y != 0 {
+				top = y
+				break
+			}
+		}
+		rows = append(rows, top)
+	}
+	return rows
+}
+
+func TestDrawSparklineRamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples int
+	}{
+		{"fewer samples than columns", 4},
+		{"one sample per column", 16},
+		{"more samples than columns", 100},
+	}
+
+	r := image.Rect(2, 1, 18, 9)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := make([]float64, tt.samples)
+			for i := range samples {
+				samples[i] = 10 + 0.5*float64(i)
+			}
+			img := NewHorizontalNibble(image.Rect(0, 0, 20, 10))
+			DrawSparkline(img, r, samples, Gray4{Y: 15})
+
+			// A rising ramp goes from the bottom left to the top right
+			// without ever moving down
+			rows := plottedRows(img, r)
+			if rows[0] != r.Max.Y-1 || rows[len(rows)-1] != r.Min.Y {
+				t.Errorf("ends at rows %d and %d, want %d and %d", rows[0], rows[len(rows)-1], r.Max.Y-1, r.Min.Y)
+			}
+			for i := 1; i < len(rows); i++ {
+				if rows[i] < 0 || rows[i] > rows[i-1] {
+					t.Errorf("rows = %v, want a non-empty upward trend", rows)
+					break
+				}
+			}
+
+			// Nothing is drawn outside r
+			for y := 0; y < 10; y++ {
+				for x := 0; x < 20; x++ {
+					if !(image.Point{x, y}).In(r) && img.Gray4At(x, y).Y != 0 {
+						t.Errorf("pixel (%d, %d) outside r is lit", x, y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDrawSparklineConnected(t *testing.T) {
+	// A jump from bottom to top fills the column where it happens
+	img := NewHorizontalNibble(image.Rect(0, 0, 4, 6))
+	DrawSparkline(img, img.Rect, []float64{0, 0, 1, 1}, Gray4{Y: 9})
+	for y := 0; y < 6; y++ {
+		if got := img.Gray4At(2, y).Y; got != 9 {
+			t.Errorf("Gray4At(2, %d) = %d, want 9", y, got)
+		}
+	}
+}
+
+func TestDrawSparklineFlat(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 6, 5))
+	DrawSparkline(img, img.Rect, []float64{3, 3, 3}, Gray4{Y: 15})
+	for x, y := range plottedRows(img, img.Rect) {
+		if y != 2 {
+			t.Errorf("column %d plotted at row %d, want the middle row 2", x, y)
+		}
+	}
+
+	// No samples draw nothing
+	img = NewHorizontalNibble(image.Rect(0, 0, 6, 5))
+	DrawSparkline(img, img.Rect, nil, Gray4{Y: 15})
+	for _, b := range img.Pix {
+		if b != 0 {
+			t.Fatalf("DrawSparkline(nil) drew %X", img.Pix)
+		}
+	}
+}
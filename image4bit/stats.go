@@ -0,0 +1,79 @@
+package image4bit
+
+// EstimateRelativePower returns a rough estimate of the panel current needed
+// to show p, normalized to [0, 1] where 1 is an all-white frame of the same
+// size and 0 is all black.
+//
+// OLED current scales with how many pixels are lit and how brightly, so this
+// is simply the mean gray level divided by 15. It ignores the contrast
+// setting, the grayscale table and the panel's fixed overhead, and is meant
+// only for relative comparisons such as battery budgeting.
+func EstimateRelativePower(p *HorizontalNibble) float64 {
+	r := p.Rect
+	if r.Empty() {
+		return 0
+	}
+	var sum int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sum += int(p.nibble(x, y))
+		}
+	}
+	return float64(sum) / float64(15*r.Dx()*r.Dy())
+}
+
+// Histogram returns how many pixels of p have each of the 16 gray levels,
+// for example to pick a contrast setting from a frame's distribution.
+// Whole bytes are counted one nibble at a time; when p.Rect has an odd
+// width, only the pixel of the last byte in each row that lies within the
+// bounds is counted, so the counts always sum to the number of pixels.
+func (p *HorizontalNibble) Histogram() [16]int {
+	var h [16]int
+	r := p.Rect
+	if r.Empty() {
+		return h
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		if r.Min.X%2 != 0 {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				h[p.nibble(x, y)]++
+			}
+			continue
+		}
+		x1 := r.Max.X
+		if x1%2 != 0 {
+			x1--
+			h[p.nibble(x1, y)]++
+		}
+		start, _ := p.pixOffset(r.Min.X, y)
+		for _, b := range p.Pix[start : start+(x1-r.Min.X)/2] {
+			h[b>>4]++
+			h[b&0x0F]++
+		}
+	}
+	return h
+}
+
+// AutoContrast suggests a contrast register value (command 0xC1, as taken
+// by Dev.SetContrast) for showing p, from its histogram: the darker the
+// frame on average, the higher the contrast, so sparse content stays
+// readable while mostly lit frames are dimmed.
+//
+// The mapping is linear in the mean gray level m (0-15), rounded to the
+// nearest integer:
+//
+//	contrast = 255 - 128*m/15
+//
+// so an all-black frame gives 255 and an all-white one 127. An empty image
+// gives 255.
+func AutoContrast(p *HorizontalNibble) byte {
+	var n, sum int
+	for level, count := range p.Histogram() {
+		n += count
+		sum += level * count
+	}
+	if n == 0 {
+		return 255
+	}
+	return byte(255 - (128*sum+15*n/2)/(15*n))
+}
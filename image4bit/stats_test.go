@@ -0,0 +1,104 @@
+package image4bit
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestEstimateRelativePower(t *testing.T) {
+	fill := func(v uint8) *HorizontalNibble {
+		img := NewHorizontalNibble(image.Rect(0, 0, 16, 4))
+		img.fill(img.Rect, v)
+		return img
+	}
+	half := NewHorizontalNibble(image.Rect(0, 0, 16, 4))
+	half.fill(image.Rect(0, 0, 8, 4), 15)
+
+	tests := []struct {
+		name string
+		img  *HorizontalNibble
+		want float64
+	}{
+		{"all black", fill(0), 0},
+		{"all white", fill(15), 1},
+		{"half gray", fill(8), 8.0 / 15},
+		{"half lit", half, 0.5},
+		{"empty", NewHorizontalNibble(image.Rectangle{}), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateRelativePower(tt.img); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EstimateRelativePower() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	// 8x2: a white quarter, a level 5 quarter and black elsewhere, with one
+	// level 9 pixel
+	img := NewHorizontalNibble(image.Rect(0, 0, 8, 2))
+	img.FillRect(image.Rect(0, 0, 4, 1), Gray4{Y: 15})
+	img.FillRect(image.Rect(4, 1, 8, 2), Gray4{Y: 5})
+	img.SetGray4(5, 0, Gray4{Y: 9})
+
+	want := [16]int{0: 7, 5: 4, 9: 1, 15: 4}
+	if got := img.Histogram(); got != want {
+		t.Errorf("Histogram() = %v, want %v", got, want)
+	}
+
+	// An odd-width sub-image does not count the pixel past its edge
+	sub := img.SubImage(image.Rect(2, 0, 7, 2))
+	want = [16]int{0: 4, 5: 3, 9: 1, 15: 2}
+	if got := sub.Histogram(); got != want {
+		t.Errorf("sub-image Histogram() = %v, want %v", got, want)
+	}
+
+	// Images starting on an odd column
+	odd := NewHorizontalNibble(image.Rect(1, 0, 5, 1))
+	odd.SetGray4(2, 0, Gray4{Y: 3})
+	if got, want := odd.Histogram(), [16]int{0: 3, 3: 1}; got != want {
+		t.Errorf("odd-origin Histogram() = %v, want %v", got, want)
+	}
+
+	if got := NewHorizontalNibble(image.Rectangle{}).Histogram(); got != [16]int{} {
+		t.Errorf("empty Histogram() = %v, want all zero", got)
+	}
+}
+
+func TestAutoContrast(t *testing.T) {
+	// Mostly dark: a short white label on black
+	dark := NewHorizontalNibble(image.Rect(0, 0, 32, 8))
+	dark.FillRect(image.Rect(2, 2, 10, 6), Gray4{Y: 15})
+
+	// Mostly light: the same label inverted
+	light := NewHorizontalNibble(image.Rect(0, 0, 32, 8))
+	light.Fill(Gray4{Y: 15})
+	light.FillRect(image.Rect(2, 2, 10, 6), Gray4{})
+
+	d, l := AutoContrast(dark), AutoContrast(light)
+	if d <= l {
+		t.Errorf("AutoContrast(dark) = %d, AutoContrast(light) = %d, want dark higher", d, l)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		level uint8
+		want  byte
+	}{
+		{"black", 0, 255},
+		{"mid gray", 6, 204},
+		{"white", 15, 127},
+	} {
+		img := NewHorizontalNibble(image.Rect(0, 0, 4, 2))
+		img.Fill(Gray4{Y: tt.level})
+		if got := AutoContrast(img); got != tt.want {
+			t.Errorf("AutoContrast(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	if got := AutoContrast(NewHorizontalNibble(image.Rectangle{})); got != 255 {
+		t.Errorf("AutoContrast(empty) = %d, want 255", got)
+	}
+}
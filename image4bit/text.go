@@ -0,0 +1,165 @@
+package image4bit
+
+import (
+	"image"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// textFace is the bitmap font used by the text helpers.
+var textFace = basicfont.Face7x13
+
+// textMask rasterizes s with textFace into an alpha mask whose origin is the
+// top-left corner of the text's line box.
+func textMask(s string) *image.Alpha {
+	w := font.MeasureString(textFace, s).Ceil()
+	h := textFace.Ascent + textFace.Descent
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	d := font.Drawer{
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: textFace,
+		Dot:  fixed.P(0, textFace.Ascent),
+	}
+	d.DrawString(s)
+	return mask
+}
+
+// DrawTextRotated draws s onto p with color c, rotated clockwise by the given
+// number of quarter turns (1 = 90°, 2 = 180°, 3 = 270°; other values are
+// taken modulo 4).
+//
+// (x, y) is the top-left corner of the rotated text's bounding box, so a
+// 90° label occupies 13 columns starting at x and grows downwards from y.
+// Only the glyph pixels are written; the background is left untouched.
+// Pixels outside p's bounds are clipped.
+func DrawTextRotated(p *HorizontalNibble, x, y int, s string, c Gray4, quarterTurns int) {
+	mask := textMask(s)
+	w, h := mask.Rect.Dx(), mask.Rect.Dy()
+	turns := ((quarterTurns % 4) + 4) % 4
+
+	for v := 0; v < h; v++ {
+		for u := 0; u < w; u++ {
+			if mask.AlphaAt(u, v).A < 0x80 {
+				continue
+			}
+			var dx, dy int
+			switch turns {
+			case 0:
+				dx, dy = u, v
+			case 1:
+				dx, dy = h-1-v, u
+			case 2:
+				dx, dy = w-1-u, h-1-v
+			case 3:
+				dx, dy = v, w-1-u
+			}
+			p.SetGray4(x+dx, y+dy, c)
+		}
+	}
+}
+
+// GlyphCache stores pre-rendered glyphs of the text font, keyed by rune, for
+// DrawCachedText. Glyphs are rendered on first use and kept for the lifetime
+// of the cache. The zero value is ready to use. A GlyphCache is not safe for
+// concurrent use.
+type GlyphCache struct {
+	glyphs map[rune]glyph
+
+	// render rasterizes a single glyph (renderGlyph if nil)
+	render func(r rune) glyph
+}
+
+// glyph is a cached glyph image whose lit pixels are 15. The image width is
+// the advance rounded up to even.
+type glyph struct {
+	img     *HorizontalNibble
+	advance int
+}
+
+// renderGlyph rasterizes r with textFace.
+func renderGlyph(r rune) glyph {
+	mask := textMask(string(r))
+	w, h := mask.Rect.Dx(), mask.Rect.Dy()
+	img := NewHorizontalNibble(image.Rect(0, 0, w+w%2, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if mask.AlphaAt(x, y).A >= 0x80 {
+				img.setNibble(x, y, 0x0F)
+			}
+		}
+	}
+	return glyph{img: img, advance: w}
+}
+
+// glyph returns the cached glyph of r, rendering it on first use.
+func (c *GlyphCache) glyph(r rune) glyph {
+	if g, ok := c.glyphs[r]; ok {
+		return g
+	}
+	if c.glyphs == nil {
+		c.glyphs = make(map[rune]glyph)
+	}
+	render := c.render
+	if render == nil {
+		render = renderGlyph
+	}
+	g := render(r)
+	c.glyphs[r] = g
+	return g
+}
+
+// DrawCachedText draws s onto p with color c like DrawTextRotated with no
+// rotation, blitting glyphs from cache instead of rasterizing the font on
+// every call. (x, y) is the top-left corner of the text's line box.
+func DrawCachedText(p *HorizontalNibble, x, y int, s string, c Gray4, cache *GlyphCache) {
+	for _, r := range s {
+		g := cache.glyph(r)
+		for gy := 0; gy < g.img.Rect.Dy(); gy++ {
+			for gx := 0; gx < g.advance; gx++ {
+				if g.img.nibble(gx, gy) != 0 {
+					p.SetGray4(x+gx, y+gy, c)
+				}
+			}
+		}
+		x += g.advance
+	}
+}
+
+// DrawText draws s onto dst with face, starting with the dot at (x, y) on
+// the baseline, as font.Drawer does. The glyph coverage blends each pixel
+// from its current level towards level, so anti-aliased faces get smooth
+// edges while bitmap faces such as basicfont.Face7x13 draw solid level.
+// Kerning is applied between runes, and pixels outside dst's bounds are
+// clipped.
+func DrawText(dst *HorizontalNibble, face font.Face, x, y int, level Gray4, s string) {
+	dot := fixed.P(x, y)
+	prev := rune(-1)
+	for _, r := range s {
+		if prev >= 0 {
+			dot.X += face.Kern(prev, r)
+		}
+		prev = r
+		dr, mask, mp, advance, ok := face.Glyph(dot, r)
+		if !ok {
+			continue
+		}
+		dot.X += advance
+
+		off := mp.Sub(dr.Min)
+		dr = dr.Intersect(dst.Rect)
+		for py := dr.Min.Y; py < dr.Max.Y; py++ {
+			for px := dr.Min.X; px < dr.Max.X; px++ {
+				_, _, _, a := mask.At(px+off.X, py+off.Y).RGBA()
+				if a == 0 {
+					continue
+				}
+				old := uint32(dst.nibble(px, py))
+				v := (old*(0xFFFF-a) + uint32(level.Y&0x0F)*a + 0x7FFF) / 0xFFFF
+				dst.setNibble(px, py, uint8(v))
+			}
+		}
+	}
+}
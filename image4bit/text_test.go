@@ -0,0 +1,123 @@
+package image4bit
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestDrawTextRotated90(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 16, 8))
+	DrawTextRotated(img, 0, 0, "L", Gray4{Y: 15}, 1)
+
+	// Unrotated, "L" has its stem in column 0 (rows 2-10) and its foot in
+	// row 10 (columns 0-5). Rotated 90° clockwise the stem becomes row 0
+	// (columns 2-10) and the foot becomes column 2 (rows 0-5).
+	lit := []image.Point{
+		{2, 0}, {6, 0}, {10, 0}, // stem
+		{2, 3}, {2, 5}, // foot
+	}
+	for _, pt := range lit {
+		if got := img.Gray4At(pt.X, pt.Y); got.Y != 15 {
+			t.Errorf("Gray4At(%d, %d).Y = %d, want 15", pt.X, pt.Y, got.Y)
+		}
+	}
+
+	dark := []image.Point{{0, 0}, {11, 0}, {2, 6}, {5, 3}}
+	for _, pt := range dark {
+		if got := img.Gray4At(pt.X, pt.Y); got.Y != 0 {
+			t.Errorf("Gray4At(%d, %d).Y = %d, want 0", pt.X, pt.Y, got.Y)
+		}
+	}
+}
+
+func TestDrawTextRotatedClips(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 4, 4))
+
+	// Must not panic when the text extends past the image
+	for turns := -1; turns <= 4; turns++ {
+		DrawTextRotated(img, -3, -3, "Hello", Gray4{Y: 15}, turns)
+	}
+}
+
+func TestDrawCachedText(t *testing.T) {
+	renders := map[rune]int{}
+	cache := &GlyphCache{render: func(r rune) glyph {
+		renders[r]++
+		return renderGlyph(r)
+	}}
+
+	img := NewHorizontalNibble(image.Rect(0, 0, 32, 16))
+	DrawCachedText(img, 1, 0, "LL", Gray4{Y: 9}, cache)
+	DrawCachedText(img, 1, 0, "L", Gray4{Y: 9}, cache)
+	if renders['L'] != 1 {
+		t.Errorf("'L' rendered %d times, want 1", renders['L'])
+	}
+
+	// The result matches the uncached renderer
+	want := NewHorizontalNibble(img.Rect)
+	DrawTextRotated(want, 1, 0, "LL", Gray4{Y: 9}, 0)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			if got, w := img.Gray4At(x, y), want.Gray4At(x, y); got != w {
+				t.Fatalf("Gray4At(%d, %d) = %d, want %d", x, y, got.Y, w.Y)
+			}
+		}
+	}
+
+	// Clipping past the image must not panic
+	DrawCachedText(img, -5, 10, "Hello", Gray4{Y: 15}, &GlyphCache{})
+}
+
+func TestDrawText(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 16, 14))
+	img.Fill(Gray4{Y: 2})
+
+	// Face7x13 has an ascent of 11, so with the baseline at row 11 "L" has
+	// its stem in column x (rows 2-10) and its foot in row 10
+	DrawText(img, basicfont.Face7x13, 3, 11, Gray4{Y: 12}, "L")
+	for _, pt := range []image.Point{{3, 2}, {3, 6}, {3, 10}, {5, 10}, {8, 10}} {
+		if got := img.Gray4At(pt.X, pt.Y).Y; got != 12 {
+			t.Errorf("Gray4At(%d, %d).Y = %d, want 12", pt.X, pt.Y, got)
+		}
+	}
+	for _, pt := range []image.Point{{3, 1}, {4, 6}, {3, 11}, {9, 10}} {
+		if got := img.Gray4At(pt.X, pt.Y).Y; got != 2 {
+			t.Errorf("Gray4At(%d, %d).Y = %d, want 2 (untouched)", pt.X, pt.Y, got)
+		}
+	}
+
+	// Must not panic when the text extends past the image
+	DrawText(img, basicfont.Face7x13, -5, 4, Gray4{Y: 15}, "Hello, world")
+}
+
+// halfFace is a font.Face whose glyphs are 2x2 squares above the baseline
+// at half coverage, as an anti-aliased edge would be.
+type halfFace struct {
+	font.Face
+}
+
+func (halfFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	x, y := dot.X.Round(), dot.Y.Round()
+	return image.Rect(x, y-2, x+2, y), image.NewUniform(color.Alpha{A: 0x80}), image.Point{}, fixed.I(2), true
+}
+
+func (halfFace) Kern(r0, r1 rune) fixed.Int26_6 { return fixed.I(1) }
+
+func TestDrawTextCoverage(t *testing.T) {
+	img := NewHorizontalNibble(image.Rect(0, 0, 8, 2))
+	img.Fill(Gray4{Y: 4})
+	DrawText(img, halfFace{}, 0, 2, Gray4{Y: 14}, "ab")
+
+	// Half coverage lands halfway between 4 and 14; kerning leaves a gap
+	want := []uint8{9, 9, 4, 9, 9, 4, 4, 4}
+	for x, level := range want {
+		if got := img.Gray4At(x, 0).Y; got != level {
+			t.Errorf("Gray4At(%d, 0).Y = %d, want %d", x, got, level)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package image4bit
+
+import (
+	"image"
+	"image/color"
+)
+
+// VerticalNibble is a 4-bit grayscale image where pixels are stored in
+// vertical nibble packing. Each byte contains 2 pixels of the same column:
+// high nibble = top pixel, low nibble = bottom pixel.
+//
+// With the display mounted rotated by 90° or 270°, vertically adjacent
+// pixels of the application's image are horizontally adjacent on the panel.
+// The ssd1322 driver's Draw therefore copies a VerticalNibble image into its
+// frame buffer by moving whole bytes (swapping their nibbles for 270°)
+// instead of repacking every pixel, as long as the image's pixel pairs line
+// up with the frame's; other rows and columns fall back to per-pixel drawing.
+type VerticalNibble struct {
+	Pix    []byte          // Pixel data (2 pixels per byte)
+	Stride int             // Bytes per pair of rows
+	Rect   image.Rectangle // Image bounds
+}
+
+// NewVerticalNibble creates a new VerticalNibble image with the specified bounds.
+// The height must be even (since 2 pixels per byte).
+func NewVerticalNibble(r image.Rectangle) *VerticalNibble {
+	w, h := r.Dx(), r.Dy()
+	if w < 0 || h < 0 {
+		return &VerticalNibble{Rect: r}
+	}
+	if h%2 != 0 {
+		panic("image4bit: height must be even")
+	}
+
+	return &VerticalNibble{
+		Pix:    make([]byte, w*h/2),
+		Stride: w,
+		Rect:   r,
+	}
+}
+
+// ColorModel returns the color model of the image.
+func (p *VerticalNibble) ColorModel() color.Model {
+	return Gray4Model
+}
+
+// Bounds returns the image bounds.
+func (p *VerticalNibble) Bounds() image.Rectangle {
+	return p.Rect
+}
+
+// At returns the color of the pixel at (x, y).
+// It implements the image.Image interface.
+func (p *VerticalNibble) At(x, y int) color.Color {
+	return p.Gray4At(x, y)
+}
+
+// Gray4At returns the Gray4 color of the pixel at (x, y).
+func (p *VerticalNibble) Gray4At(x, y int) Gray4 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return Gray4{}
+	}
+	offset, shift := p.pixOffset(x, y)
+	return Gray4{Y: (p.Pix[offset] >> shift) & 0x0F}
+}
+
+// Set sets the color of the pixel at (x, y).
+func (p *VerticalNibble) Set(x, y int, c color.Color) {
+	p.SetGray4(x, y, Gray4Model.Convert(c).(Gray4))
+}
+
+// SetGray4 sets the Gray4 color of the pixel at (x, y).
+// This is faster than Set() as it doesn't require color conversion.
+func (p *VerticalNibble) SetGray4(x, y int, c Gray4) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+	offset, shift := p.pixOffset(x, y)
+	// Clear the nibble and set the new value
+	p.Pix[offset] = (p.Pix[offset] &^ (0x0F << shift)) | ((c.Y & 0x0F) << shift)
+}
+
+// pixOffset returns the byte offset and bit shift for the pixel at (x, y).
+// Memory layout: each byte contains 2 pixels vertically, counted from the
+// top of the image.
+// High nibble (shift 4) = even row (top pixel)
+// Low nibble (shift 0) = odd row (bottom pixel)
+func (p *VerticalNibble) pixOffset(x, y int) (offset int, shift uint) {
+	row := y - p.Rect.Min.Y
+	offset = row/2*p.Stride + (x - p.Rect.Min.X)
+	shift = uint(4 * (1 - (row & 1)))
+	return
+}
@@ -0,0 +1,87 @@
+package image4bit
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNewVerticalNibble(t *testing.T) {
+	img := NewVerticalNibble(image.Rect(0, 0, 3, 4))
+	if img.Stride != 3 || len(img.Pix) != 6 {
+		t.Errorf("Stride = %d, len(Pix) = %d, want 3 and 6", img.Stride, len(img.Pix))
+	}
+	if img.Bounds() != image.Rect(0, 0, 3, 4) {
+		t.Errorf("Bounds() = %v, want %v", img.Bounds(), image.Rect(0, 0, 3, 4))
+	}
+	if img.ColorModel() != Gray4Model {
+		t.Error("ColorModel() should be Gray4Model")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewVerticalNibble with odd height should panic")
+		}
+	}()
+	NewVerticalNibble(image.Rect(0, 0, 4, 3))
+}
+
+func TestVerticalNibbleLayout(t *testing.T) {
+	img := NewVerticalNibble(image.Rect(0, 0, 2, 4))
+	img.SetGray4(0, 0, Gray4{Y: 0x1})
+	img.SetGray4(0, 1, Gray4{Y: 0x2})
+	img.SetGray4(1, 0, Gray4{Y: 0x3})
+	img.SetGray4(1, 3, Gray4{Y: 0x4})
+
+	// Column pairs: byte (x, rows 0-1) then (x, rows 2-3)
+	want := []byte{0x12, 0x30, 0x00, 0x04}
+	if !bytes.Equal(img.Pix, want) {
+		t.Errorf("Pix = %X, want %X", img.Pix, want)
+	}
+
+	// Pairs count from the top of the bounds
+	img = NewVerticalNibble(image.Rect(5, 3, 6, 5))
+	img.SetGray4(5, 3, Gray4{Y: 0xA})
+	img.SetGray4(5, 4, Gray4{Y: 0xB})
+	if img.Pix[0] != 0xAB {
+		t.Errorf("offset bounds Pix[0] = %02X, want AB", img.Pix[0])
+	}
+}
+
+func TestVerticalNibbleAllGrayLevels(t *testing.T) {
+	img := NewVerticalNibble(image.Rect(0, 0, 4, 8))
+	for level := 0; level < 16; level++ {
+		x, y := level%4, level/4*2+level%2
+		img.SetGray4(x, y, Gray4{Y: uint8(level)})
+	}
+	for level := 0; level < 16; level++ {
+		x, y := level%4, level/4*2+level%2
+		if got := img.Gray4At(x, y).Y; got != uint8(level) {
+			t.Errorf("Gray4At(%d, %d) = %d, want %d", x, y, got, level)
+		}
+		if got := img.At(x, y).(Gray4).Y; got != uint8(level) {
+			t.Errorf("At(%d, %d) = %d, want %d", x, y, got, level)
+		}
+	}
+
+	// Set converts through Gray4Model
+	img.Set(0, 0, color.Gray{Y: 0xFF})
+	if got := img.Gray4At(0, 0).Y; got != 15 {
+		t.Errorf("after Set(white) Gray4At(0, 0) = %d, want 15", got)
+	}
+}
+
+func TestVerticalNibbleOutOfBounds(t *testing.T) {
+	img := NewVerticalNibble(image.Rect(0, 0, 2, 2))
+	for _, p := range []image.Point{{-1, 0}, {2, 0}, {0, -1}, {0, 2}} {
+		img.SetGray4(p.X, p.Y, Gray4{Y: 15})
+		img.Set(p.X, p.Y, Gray4{Y: 15})
+		if got := img.Gray4At(p.X, p.Y); got != (Gray4{}) {
+			t.Errorf("Gray4At(%d, %d) = %v, want Gray4{}", p.X, p.Y, got)
+		}
+	}
+	if !bytes.Equal(img.Pix, []byte{0, 0}) {
+		t.Errorf("out-of-bounds writes changed Pix to %X", img.Pix)
+	}
+}
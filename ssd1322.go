@@ -8,97 +8,436 @@ package ssd1322
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"image"
 	"image/color"
-	"image/draw"
+	"image/gif"
+	"math"
+	"math/bits"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/flavioheleno/ssd1322/image4bit"
+	xdraw "golang.org/x/image/draw"
 	"periph.io/x/conn/v3"
 	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
 	"periph.io/x/conn/v3/spi"
 )
 
+// Orientation is the clockwise rotation of the panel as mounted, relative to
+// its native landscape layout.
+//
+// Rotate180 uses the hardware remap and is equivalent to Opts.Rotated (the
+// two toggle each other). The hardware cannot rotate by 90°, so for Rotate90
+// and Rotate270 the driver swaps the dimensions reported by Bounds and
+// rotates the images given to Draw, DrawDithered and FitImage in software.
+// Draw moves whole bytes for an image4bit.VerticalNibble image, whose
+// vertical pixel pairs are horizontal pairs of the rotated panel.
+// All other methods, including Write, SetBuffer, Image, MarkDirty and the
+// region methods, keep using the native layout and coordinates.
+type Orientation int
+
+const (
+	Rotate0   Orientation = iota // Native landscape layout
+	Rotate90                     // Rotated 90° clockwise (native left edge at the top)
+	Rotate180                    // Upside down
+	Rotate270                    // Rotated 270° clockwise (native right edge at the top)
+)
+
+// SPI clock frequencies in Hz.
+const (
+	defaultSPIHz = 10_000_000
+	maxSPIHz     = 20_000_000 // Datasheet maximum (50ns serial clock cycle)
+)
+
 // Opts is the configuration for the SSD1322 display.
 type Opts struct {
 	// Display dimensions in pixels
-	W int // Width (default: 256, must be even and ≤480)
+	W int // Width (default: 256, must be ≤480; odd widths are padded, see NewSPI)
 	H int // Height (default: 64, must be ≤128)
 
 	// Rotation and mirroring
+	//
+	// MirrorX and MirrorY flip the column and COM scan direction
+	// respectively. Rotated is equivalent to mirroring on both axes, so the
+	// flags combine by toggling: Rotated with MirrorX yields a vertical
+	// mirror only, and MirrorX with MirrorY is the same as Rotated.
 	Rotated       bool // 180° rotation
+	MirrorX       bool // Horizontal mirror (column address remap)
+	MirrorY       bool // Vertical mirror (COM scan direction remap)
 	Sequential    bool // Sequential COM pin configuration
 	SwapTopBottom bool // Swap top/bottom display halves
 
+	// Mounting rotation, including 90° and 270° in software (see
+	// Orientation)
+	Orientation Orientation
+
 	// Optional hardware reset pin
 	RST gpio.PinIO // Reset pin (optional, nil if not used)
+
+	// SPI bus settings. The SSD1322 supports clocks up to 20MHz (maxSPIHz)
+	// in Mode0 (CPOL=0, CPHA=0) or Mode3 (CPOL=1, CPHA=1).
+	SPIHz   int      // Clock frequency in Hz (default: 10MHz)
+	SPIMode spi.Mode // Clock polarity and phase (default: Mode0)
+
+	// Display clock (command 0xB3), setting the refresh rate. DCLK is the
+	// oscillator divided by 2^ClockDivider (0-10), and OscFreq (0-15)
+	// raises the oscillator frequency. Zero selects the defaults of 2
+	// (divide by 4) and 15 (fastest); use SetClockDivider for a zero value.
+	ClockDivider uint8
+	OscFreq      uint8
+
+	// Largest single SPI transfer in bytes (default: 4096, the usual
+	// spidev bufsiz). Longer pixel data is split into several transfers
+	// with DC held high; commands are never split.
+	MaxTxSize int
+
+	// Extra settle time after specific init steps, for slow or clone panels
+	// that show garbage on the first frame (zero means no delay)
+	UnlockDelay    time.Duration // After the command unlock
+	RemapDelay     time.Duration // After the remap command
+	DisplayOnDelay time.Duration // After turning the display ON
+
+	// Time source for delays and timed operations (optional, see Clock)
+	Clock Clock
+
+	// Called with a copy of every command (isData false) and data (isData
+	// true) payload before it is transmitted, for logging the byte stream
+	// or asserting it in tests. Bytes queued together are reported
+	// together, and data is reported before it is split into transfers of
+	// MaxTxSize bytes; RAM reads are not reported. The hook runs with the
+	// device lock held and must not call Dev methods. Nil (the default)
+	// disables tracing.
+	OnCommand func(isData bool, payload []byte)
+
+	// Number of recent RAM writes kept for debugging (0 disables the
+	// history, see TransmitHistory)
+	TransmitHistory int
+
+	// Width limit and RAM width used for centering (default: 480, the
+	// SSD1322's RAM). Only simulated devices, such as a virtual canvas
+	// spanning several panels, should raise it: column addresses past the
+	// controller's range do not fit the address commands and are truncated.
+	MaxColumns int
+
+	// Disable the automatic centering on the 480-column RAM: display column
+	// x is written to RAM column x/2 as given. The caller becomes
+	// responsible for placing content where the panel shows it, since most
+	// panels narrower than 480 pixels are wired to the middle of the RAM
+	// and will show nothing at column 0. Useful for custom layouts that
+	// manage their own addressing.
+	RawAddressing bool
+
+	// Reorder the pixel data of every row for panel variants whose column
+	// routing expects interleaved bytes: each row of a write sends the
+	// bytes at even positions first, then those at odd positions, so a row
+	// of bytes b0 b1 b2 b3 b4 is sent as b0 b2 b4 b1 b3. Positions are
+	// counted from the start of the written window. The frame buffers keep
+	// the normal layout.
+	ColumnInterleave bool
+
+	// Mirror every transmitted frame in software, for mountings the
+	// hardware remap (FlipH, FlipV) cannot express. The frame buffers,
+	// Image and all drawing coordinates stay unflipped; only the data and
+	// window sent to the RAM are mirrored, across the frame buffer width
+	// rounded up to even. Partial updates are written to the mirrored
+	// window.
+	SoftwareFlipH bool
+	SoftwareFlipV bool
+
+	// Skip the column, row and RAM write commands when a write targets the
+	// same window as the previous one with no other command in between,
+	// sending only the data. The controller wraps back to the start of the
+	// window after each full write, so back-to-back writes of the same
+	// window (e.g. streaming frames) land in place.
+	ReuseWindow bool
+
+	// Record every changed byte found by the differential update, for
+	// debugging over-transmission (see DiffDetails). Off by default, as it
+	// allocates on every diff.
+	RecordDiffDetails bool
+
+	// Maximum number of separate rectangles a differential update may
+	// write. When above 1, disjoint changed areas (e.g. two opposite
+	// corners) are sent as separate windows instead of one bounding box;
+	// if the changes form more regions than this, the single bounding box
+	// is used. 0 or 1 always writes the bounding box.
+	MaxDiffRegions int
+}
+
+// ByteChange is one frame buffer byte changed between two frames, as
+// reported by DiffDetails.
+type ByteChange struct {
+	Offset int  // Index into the frame buffer (row * stride + column / 2)
+	Old    byte // Value in the last displayed frame
+	New    byte // Value in the new frame
+}
+
+// defaultMaxTxSize is the default limit for a single SPI transfer.
+const defaultMaxTxSize = 4096
+
+// maxTxSize returns the largest single SPI transfer, honouring MaxTxSize.
+func (o *Opts) maxTxSize() int {
+	if o.MaxTxSize > 0 {
+		return o.MaxTxSize
+	}
+	return defaultMaxTxSize
+}
+
+// Default display clock settings (see Opts.ClockDivider).
+const (
+	defaultClockDivider = 0x2
+	defaultOscFreq      = 0xF
+)
+
+// clock returns the parameter of the display clock command (0xB3): the
+// oscillator frequency in the high nibble and the divider in the low one.
+func (o *Opts) clock() byte {
+	div, osc := o.ClockDivider, o.OscFreq
+	if div == 0 {
+		div = defaultClockDivider
+	}
+	if osc == 0 {
+		osc = defaultOscFreq
+	}
+	return osc<<4 | div
+}
+
+// checkClock validates display clock settings. Divide ratios above 2^10
+// are reserved.
+func checkClock(divider, oscFreq uint8) error {
+	if divider > 10 {
+		return fmt.Errorf("ssd1322: clock divider must be between 0 and 10 (got %d)", divider)
+	}
+	if oscFreq > 0x0F {
+		return fmt.Errorf("ssd1322: oscillator frequency must be between 0 and 15 (got %d)", oscFreq)
+	}
+	return nil
+}
+
+// columns returns the RAM width in pixels, honouring MaxColumns.
+func (o *Opts) columns() int {
+	if o.MaxColumns > 0 {
+		return o.MaxColumns
+	}
+	return ramColumns
+}
+
+// TransmitRecord describes one RAM write recorded by the transmit history.
+type TransmitRecord struct {
+	Rect  image.Rectangle // Region written, widened to whole bytes
+	Bytes int             // Pixel data bytes sent
+	Time  time.Time       // When the write completed, from the device clock
+}
+
+// Clock is the time source used for all delays and timed operations such as
+// the init settle delays and FadeContrast. A nil Now or Sleep falls back to
+// the time package, so the zero Clock is the real clock.
+type Clock struct {
+	Now   func() time.Time
+	Sleep func(time.Duration)
+}
+
+// now returns the current time.
+func (c Clock) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// sleep pauses for t.
+func (c Clock) sleep(t time.Duration) {
+	if c.Sleep != nil {
+		c.Sleep(t)
+		return
+	}
+	time.Sleep(t)
 }
 
 // Dev is the device handle for the SSD1322 display.
+//
+// Dev is safe for concurrent use: its methods take an internal lock, so a
+// command and its parameters or pixel data are never interleaved with
+// another goroutine's transfers, e.g. when frames are drawn on one
+// goroutine while the contrast is adjusted on another. Long-running
+// methods (FadeContrast, CalibrateContrast, ScrollContentVertical) take
+// the lock for each step rather than for their whole duration, and frame
+// pacing (see SetMinFrameInterval) waits without the lock. Images
+// returned by Image and BeginFrame are not guarded; modify them from other
+// goroutines through Update.
 type Dev struct {
 	// Communication
-	c   conn.Conn   // SPI connection
-	dc  gpio.PinOut // Data/Command pin
-	rst gpio.PinIO  // Reset pin (optional)
+	bus    transport  // Command and data transfers (SPI, or the parallel bus of NewParallel)
+	rst    gpio.PinIO // Reset pin (optional)
+	window []byte     // Window commands of the RAM write in progress (see Opts.ReuseWindow)
 
 	// Display geometry
+	opts         Opts // Options the device was initialized with
 	rect         image.Rectangle
-	columnOffset int // For centering on 480-column RAM
+	columnOffset int // For centering on 480-column RAM (0 with RawAddressing)
+	panOffset    int // Horizontal pan on top of columnOffset (see SetStartColumn)
 
 	// Pixel buffers
 	buffer []byte                      // Current frame
-	next   *image4bit.HorizontalNibble // For lazy double buffering
+	next   *image4bit.HorizontalNibble // Frame being composed by Draw
 	lastDm image4bit.HorizontalNibble  // Last displayed frame for differential updates
 
 	// Change tracking
 	minCol, maxCol int
 	minRow, maxRow int
+	dirty          []image.Rectangle // Caller-provided dirty regions (see MarkDirty)
+	lastWrite      image.Rectangle   // Region of the last RAM write, widened to whole bytes
+	history        []TransmitRecord  // Ring buffer of recent RAM writes (nil if disabled)
+	historyNext    int               // Index of the oldest record once history is full
+	diffDetails    []ByteChange      // Changes found by the last diff (see Opts.RecordDiffDetails)
+	diffRuns       []diffRun         // Changed runs found by the last diff (see Opts.MaxDiffRegions)
+	diffRect       image.Rectangle   // Bounding box found by the last diff (see DirtyRect)
+	ditherPrev     *image.Gray       // Luma of the last DrawDithered source (nil if none)
+	ditherNext     *image.Gray       // Luma of the DrawDithered source being processed
+
+	// Timing
+	clock            Clock         // Time source for all delays
+	minFrameInterval time.Duration // Minimum time between frame transmits (see SetMinFrameInterval)
+	lastFrame        time.Time     // When the last frame transmit started
+	paceWaits        int           // Times pace released the lock to wait
 
 	// State
-	halted bool
+	initialized   bool // Set once init completes successfully
+	halted        bool
+	sleeping      bool           // Display off by Sleep, with RAM kept (see Wake)
+	contrastCurve ContrastCurve  // Response curve applied by SetContrast
+	contrast      byte           // Contrast register value currently set
+	inverted      bool           // Whether the display is inverted
+	startLine     byte           // RAM row shown at the top of the panel
+	scroll        *ScrollState   // Active horizontal scroll (nil if stopped)
+	partial       *PartialWindow // Active partial display window (nil if off)
+	alert         bool           // Whether AlertStyle is on
+	alertInverted bool           // Inversion saved by AlertStyle
+	alertContrast byte           // Contrast register value saved by AlertStyle
+	grayTable     []byte         // Last custom grayscale table sent (nil if none)
+	grayCustom    bool           // Whether the custom grayscale table is active
+
+	// Background flushing (see StartAutoFlush)
+	mu       sync.Mutex    // Serializes all access to the device state and the bus (see Dev)
+	autoStop chan struct{} // Closed to stop the auto-flush goroutine (nil if not running)
+	autoDone chan error    // Receives the auto-flush result when it stops
+
+	// Callbacks
+	calibSelect func(contrast byte) bool // Picks the CalibrateContrast result (see SetCalibrationSelector)
 }
 
 // NewSPI creates a new SSD1322 device connected via SPI.
 //
-// The SPI port is configured for 10MHz, Mode0 (CPOL=0, CPHA=0), 8-bit transfers.
+// The SPI port is configured for 8-bit transfers at Opts.SPIHz in Opts.SPIMode
+// (10MHz, Mode0 (CPOL=0, CPHA=0) by default).
 // The dc (Data/Command) GPIO pin must be provided and configured as an output.
 //
 // opts can be nil to use defaults (256x64 display).
+//
+// Since each byte holds two pixels, an odd width is padded internally with
+// one dark column on the right: Bounds reports the logical width, while the
+// frame buffer, Write data and transmitted RAM window use the width rounded
+// up to even. The padding column is always sent as black.
 func NewSPI(p spi.Port, dc gpio.PinOut, opts *Opts) (*Dev, error) {
 	// Apply defaults and validate options
 	if opts == nil {
 		opts = &Opts{W: 256, H: 64}
 	}
+	if err := checkOpts(opts); err != nil {
+		return nil, err
+	}
 
-	if opts.W <= 0 || opts.W%2 != 0 || opts.W > 480 {
-		return nil, errors.New("ssd1322: width must be even and between 2 and 480")
+	// Establish SPI connection, by default in Mode0 at a conservative 10MHz
+	hz := opts.SPIHz
+	if hz == 0 {
+		hz = defaultSPIHz
 	}
-	if opts.H <= 0 || opts.H > 128 {
-		return nil, errors.New("ssd1322: height must be between 1 and 128")
+	if hz < 0 || hz > maxSPIHz {
+		return nil, fmt.Errorf("ssd1322: SPI frequency must be between 1Hz and 20MHz (got %d)", hz)
 	}
-
-	// Establish SPI connection
-	// SSD1322 supports Mode0 (CPOL=0, CPHA=0) or Mode3 (CPOL=1, CPHA=1)
-	// Using Mode0 and 10MHz (conservative, up to 20MHz supported)
-	c, err := p.Connect(10*1000000, spi.Mode0, 8)
+	c, err := p.Connect(physic.Frequency(hz)*physic.Hertz, opts.SPIMode, 8)
 	if err != nil {
 		return nil, err
 	}
+	return newDev(&spiTransport{c: c, dc: dcPin{pin: dc}, maxTx: opts.maxTxSize()}, opts)
+}
+
+// NewParallel creates a new SSD1322 device wired for its 8-bit 8080-series
+// parallel interface, with every bus line on a GPIO pin. The 6800-series
+// interface is not supported.
+//
+// data holds the D0 to D7 pins. Each byte is put on them and latched by a
+// low pulse on wr, with dc selecting commands or data as over SPI; only
+// the data pins whose level changes are written. cs is held low during
+// each transfer and may be nil if tied low. rd is held high, and may also
+// be nil if tied high: the driver never reads over the parallel bus, so
+// ReadRAM and VerifyLastWrite return an error.
+//
+// The bus is bit-banged, costing up to ten GPIO writes per byte, so its
+// throughput depends on how fast the host toggles its pins. The pins are
+// expected to be slower than the controller's 300ns minimum write cycle.
+//
+// opts is as for NewSPI; the SPI bus settings are ignored.
+func NewParallel(data [8]gpio.PinOut, wr, rd, dc, cs gpio.PinOut, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &Opts{W: 256, H: 64}
+	}
+	if err := checkOpts(opts); err != nil {
+		return nil, err
+	}
+	for _, pin := range data {
+		if pin == nil {
+			return nil, errors.New("ssd1322: parallel data pins must not be nil")
+		}
+	}
+	if wr == nil || dc == nil {
+		return nil, errors.New("ssd1322: parallel WR and DC pins must not be nil")
+	}
+
+	// Idle levels: chip select first, so raising WR latches nothing
+	for _, pin := range []gpio.PinOut{cs, rd, wr} {
+		if pin == nil {
+			continue
+		}
+		if err := pin.Out(gpio.High); err != nil {
+			return nil, err
+		}
+	}
+	return newDev(&parallelTransport{data: data, wr: wr, cs: cs, dc: dcPin{pin: dc}}, opts)
+}
+
+// checkOpts validates the options shared by all bus types.
+func checkOpts(opts *Opts) error {
+	if err := checkSize(opts.W, opts.H, opts.columns()); err != nil {
+		return err
+	}
+	if opts.Orientation < Rotate0 || opts.Orientation > Rotate270 {
+		return errors.New("ssd1322: invalid orientation")
+	}
+	if opts.MaxTxSize < 0 {
+		return errors.New("ssd1322: MaxTxSize must not be negative")
+	}
+	return checkClock(opts.ClockDivider, opts.OscFreq)
+}
 
-	// Create device
+// newDev creates a device sending over bus and initializes the display.
+func newDev(bus transport, opts *Opts) (*Dev, error) {
 	d := &Dev{
-		c:            c,
-		dc:           dc,
-		rst:          opts.RST,
-		rect:         image.Rect(0, 0, opts.W, opts.H),
-		columnOffset: (480 - opts.W) / 2,
-		buffer:       make([]byte, opts.W*opts.H/2),
-		minCol:       0,
-		maxCol:       opts.W - 1,
-		minRow:       0,
-		maxRow:       opts.H - 1,
+		bus:   bus,
+		rst:   opts.RST,
+		opts:  *opts,
+		clock: opts.Clock,
+	}
+	d.setSize(opts.W, opts.H)
+	if opts.TransmitHistory > 0 {
+		d.history = make([]TransmitRecord, 0, opts.TransmitHistory)
 	}
 
 	// Initialize the display
@@ -109,46 +448,369 @@ func NewSPI(p spi.Port, dc gpio.PinOut, opts *Opts) (*Dev, error) {
 	return d, nil
 }
 
+// transport carries command and data bytes to the controller, which tells
+// them apart by the level of its DC (Data/Command) line.
+type transport interface {
+	// SendCommand sends command bytes, parameters included, with DC low.
+	SendCommand(cmds []byte) error
+	// SendData sends data bytes, such as pixels for RAM, with DC high.
+	SendData(data []byte) error
+}
+
+// dcPin is a DC pin that skips the GPIO write when the pin is already known
+// to be at the requested level.
+type dcPin struct {
+	pin   gpio.PinOut
+	level gpio.Level // Level last driven on pin
+	known bool       // Whether level is valid
+}
+
+// set drives the pin to l.
+func (p *dcPin) set(l gpio.Level) error {
+	if p.known && p.level == l {
+		return nil
+	}
+	if err := p.pin.Out(l); err != nil {
+		p.known = false
+		return err
+	}
+	p.level, p.known = l, true
+	return nil
+}
+
+// spiTransport is the 4-wire SPI interface: bytes go over the SPI
+// connection and a GPIO pin drives DC (see NewSPI).
+type spiTransport struct {
+	c     conn.Conn
+	dc    dcPin
+	maxTx int // Largest single transfer (see Opts.MaxTxSize)
+}
+
+// SendCommand sends cmds in a single transfer.
+func (t *spiTransport) SendCommand(cmds []byte) error {
+	if err := t.dc.set(gpio.Low); err != nil {
+		return err
+	}
+	return t.c.Tx(cmds, nil)
+}
+
+// SendData sends data in transfers of at most maxTx bytes. The controller
+// keeps advancing its RAM address across transfers, so the split points do
+// not matter.
+func (t *spiTransport) SendData(data []byte) error {
+	if err := t.dc.set(gpio.High); err != nil {
+		return err
+	}
+	for len(data) > t.maxTx {
+		if err := t.c.Tx(data[:t.maxTx], nil); err != nil {
+			return err
+		}
+		data = data[t.maxTx:]
+	}
+	return t.c.Tx(data, nil)
+}
+
+// readData fills r with bytes clocked out of the controller with DC high,
+// over a full-duplex connection.
+func (t *spiTransport) readData(r []byte) error {
+	if err := t.dc.set(gpio.High); err != nil {
+		return err
+	}
+	return t.c.Tx(make([]byte, len(r)), r)
+}
+
+// parallelTransport is the 8080-series parallel interface, driven by
+// toggling GPIO pins (see NewParallel).
+type parallelTransport struct {
+	data   [8]gpio.PinOut // D0 to D7
+	wr, cs gpio.PinOut    // Write strobe and chip select (cs may be nil)
+	dc     dcPin
+	levels byte // Byte last put on the data pins
+	known  bool // Whether levels is valid
+}
+
+// SendCommand writes cmds with DC low.
+func (t *parallelTransport) SendCommand(cmds []byte) error {
+	return t.send(gpio.Low, cmds)
+}
+
+// SendData writes data with DC high.
+func (t *parallelTransport) SendData(data []byte) error {
+	return t.send(gpio.High, data)
+}
+
+// send writes w one byte at a time with DC at dc and cs asserted.
+func (t *parallelTransport) send(dc gpio.Level, w []byte) error {
+	if err := t.dc.set(dc); err != nil {
+		return err
+	}
+	if t.cs != nil {
+		if err := t.cs.Out(gpio.Low); err != nil {
+			return err
+		}
+	}
+	err := t.write(w)
+	if t.cs != nil {
+		if csErr := t.cs.Out(gpio.High); err == nil {
+			err = csErr
+		}
+	}
+	return err
+}
+
+// write puts each byte of w on the data pins, changing only the pins whose
+// level differs from the previous byte, and latches it with a WR pulse.
+func (t *parallelTransport) write(w []byte) error {
+	for _, b := range w {
+		for i, pin := range t.data {
+			bit := byte(1) << i
+			if t.known && (b^t.levels)&bit == 0 {
+				continue
+			}
+			if err := pin.Out(gpio.Level(b&bit != 0)); err != nil {
+				t.known = false
+				return err
+			}
+		}
+		t.levels, t.known = b, true
+
+		// The controller latches the data on the rising edge of WR
+		if err := t.wr.Out(gpio.Low); err != nil {
+			return err
+		}
+		if err := t.wr.Out(gpio.High); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ramColumns is the number of pixel columns in the controller's display RAM.
+const ramColumns = 480
+
+// Display size validation errors returned (wrapped) by NewSPI, NewParallel
+// and Reconfigure. Odd widths are valid; see NewSPI.
+var (
+	ErrWidthNotPositive  = errors.New("ssd1322: width must be positive")
+	ErrWidthTooLarge     = errors.New("ssd1322: width too large")
+	ErrHeightNotPositive = errors.New("ssd1322: height must be positive")
+	ErrHeightTooLarge    = errors.New("ssd1322: height too large")
+)
+
+// Errors returned by device operations, for matching with errors.Is.
+// ErrHalted is permanent until Reset, and ErrSleeping until Wake; errors
+// from the SPI connection are returned as reported by it.
+var (
+	ErrNotInitialized      = errors.New("ssd1322: not initialized")
+	ErrHalted              = errors.New("ssd1322: halted")
+	ErrSleeping            = errors.New("ssd1322: sleeping")
+	ErrInvalidBufferSize   = errors.New("ssd1322: invalid buffer size")
+	ErrInvalidBounds       = errors.New("ssd1322: region out of bounds")
+	ErrScrollRowOutOfRange = errors.New("ssd1322: scroll row out of range")
+)
+
+// checkSize validates display dimensions against a RAM width of maxW
+// columns.
+func checkSize(w, h, maxW int) error {
+	switch {
+	case w <= 0:
+		return fmt.Errorf("%w (got %d)", ErrWidthNotPositive, w)
+	case w > maxW:
+		return fmt.Errorf("%w (got %d, maximum %d)", ErrWidthTooLarge, w, maxW)
+	case h <= 0:
+		return fmt.Errorf("%w (got %d)", ErrHeightNotPositive, h)
+	case h > ramRows:
+		return fmt.Errorf("%w (got %d, maximum %d)", ErrHeightTooLarge, h, ramRows)
+	}
+	return nil
+}
+
+// setSize sets the display geometry and (re)allocates all frame buffers,
+// discarding any change tracking state.
+//
+// The double-buffer state is allocated up front so Write and Draw always
+// share the same, consistent view of what is on the display. This costs
+// two extra frame-sized buffers (8KiB each for 256x64).
+func (d *Dev) setSize(w, h int) {
+	// Odd widths get one padding column so the frame buffer stays in whole
+	// bytes; d.next.Rect is the padded frame, d.rect the logical bounds
+	pw := w + w%2
+	frame := image.Rect(0, 0, pw, h)
+	d.rect = image.Rect(0, 0, w, h)
+	d.columnOffset = (d.opts.columns() - pw) / 2
+	if d.opts.RawAddressing {
+		d.columnOffset = 0
+	}
+	d.buffer = make([]byte, pw*h/2)
+	d.next = image4bit.NewHorizontalNibble(frame)
+	d.lastDm = image4bit.HorizontalNibble{
+		Pix:    make([]byte, len(d.next.Pix)),
+		Stride: d.next.Stride,
+		Rect:   frame,
+	}
+	d.ditherNext = nil
+	d.clearFrames()
+}
+
+// clearFrames blanks the frame buffers in place and discards the change
+// tracking state, keeping the image returned by Image valid.
+func (d *Dev) clearFrames() {
+	clear(d.buffer)
+	clear(d.next.Pix)
+	clear(d.lastDm.Pix)
+	d.panOffset = 0
+	d.minCol, d.maxCol = 0, d.rect.Dx()-1
+	d.minRow, d.maxRow = 0, d.rect.Dy()-1
+	d.dirty = nil
+	d.ditherPrev = nil
+}
+
+// Reconfigure changes the display resolution, reallocating all buffers and
+// re-running the initialization sequence with the new MUX ratio. The frame
+// buffer and change tracking state are cleared.
+//
+// The dimensions are validated with the same rules as NewSPI. Reconfigure is
+// meant for simulated or in-memory panels (e.g. switching modes during
+// development); it is not supported on real hardware mid-session, where the
+// physical panel geometry cannot change.
+//
+// If the initialization fails the device is left uninitialized and every
+// other operation returns an error until a Reconfigure succeeds.
+func (d *Dev) Reconfigure(w, h int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.halted {
+		return ErrHalted
+	}
+	if err := checkSize(w, h, d.opts.columns()); err != nil {
+		return err
+	}
+	d.opts.W, d.opts.H = w, h
+	d.setSize(w, h)
+	return d.init(&d.opts)
+}
+
 // init sends the initialization sequence to the display.
 func (d *Dev) init(opts *Opts) error {
+	d.initialized = false
+	d.sleeping = false
+	d.lastWrite = image.Rectangle{}
+	d.diffRect = image.Rectangle{}
+
 	// Hardware reset sequence (if RST pin is provided)
 	if d.rst != nil {
 		if err := d.rst.Out(gpio.Low); err != nil {
 			return fmt.Errorf("ssd1322: failed to pull RST low: %w", err)
 		}
-		time.Sleep(200 * time.Millisecond)
+		d.delay(200 * time.Millisecond)
 
 		if err := d.rst.Out(gpio.High); err != nil {
 			return fmt.Errorf("ssd1322: failed to pull RST high: %w", err)
 		}
-		time.Sleep(200 * time.Millisecond)
+		d.delay(200 * time.Millisecond)
 	}
 
-	// Build initialization command sequence
-	cmds := []byte{
-		0xFD, 0x12, // Unlock command codes
-		0xAE,       // Display OFF
-		0xB3, 0xF2, // Clock divider and oscillator frequency
-		0xCA, byte(opts.H - 1), // MUX ratio
-		0xA2, 0x00, // Display offset
-		0xA1, 0x00, // Start line
+	// Send the configuration commands, splitting the transfer wherever a
+	// settle delay is configured
+	cmds, unlockEnd, remapEnd := initSequence(opts)
+	seq := &sequencer{d: d}
+	sent := 0
+	settle := func(t time.Duration, end int) error {
+		if t <= 0 {
+			return nil
+		}
+		if err := seq.command(cmds[sent:end]...); err != nil {
+			return err
+		}
+		if err := seq.flush(); err != nil {
+			return err
+		}
+		sent = end
+		d.delay(t)
+		return nil
+	}
+	if err := settle(opts.UnlockDelay, unlockEnd); err != nil {
+		return err
+	}
+	if err := settle(opts.RemapDelay, remapEnd); err != nil {
+		return err
 	}
 
-	// Remap settings: adjust for rotation and mirroring
-	remap1, remap2 := byte(0x14), byte(0x11)
-	if opts.Rotated {
-		remap1 = 0x06
-		remap2 = 0x11
+	if err := seq.command(cmds[sent:]...); err != nil {
+		return err
 	}
-	if opts.Sequential {
-		remap2 |= 0x01
+	d.contrast, d.inverted, d.alert = 0xFF, false, false
+	d.startLine = 0
+	d.scroll = nil
+	d.partial = nil
+	d.grayCustom = false
+
+	// Clear display RAM
+	if err := d.clearRAM(seq); err != nil {
+		return err
 	}
-	if opts.SwapTopBottom {
-		remap2 |= 0x02
+
+	// Turn display ON
+	if err := seq.command(0xAF); err != nil {
+		return err
+	}
+	if err := seq.flush(); err != nil {
+		return err
+	}
+	if opts.DisplayOnDelay > 0 {
+		d.delay(opts.DisplayOnDelay)
+	}
+	d.initialized = true
+	return nil
+}
+
+// ready returns an error if the device cannot accept commands, either
+// because init has not completed successfully or because it was halted.
+func (d *Dev) ready() error {
+	if !d.initialized {
+		return ErrNotInitialized
+	}
+	if d.halted {
+		return ErrHalted
+	}
+	if d.sleeping {
+		return ErrSleeping
 	}
+	return nil
+}
+
+// InitSequence returns the configuration command bytes that NewSPI sends to
+// the controller for opts, without talking to any hardware. opts is not
+// validated.
+//
+// At runtime the sequence may be split into several transfers by the settle
+// delays in opts, and it is followed by clearing the display RAM and the
+// Display ON command (0xAF).
+func InitSequence(opts Opts) []byte {
+	cmds, _, _ := initSequence(&opts)
+	return cmds
+}
+
+// initSequence builds the configuration command bytes, also returning the
+// offsets just past the unlock and the remap commands.
+func initSequence(opts *Opts) (cmds []byte, unlockEnd, remapEnd int) {
+	cmds = []byte{0xFD, 0x12} // Unlock command codes
+	unlockEnd = len(cmds)
+
+	cmds = append(cmds,
+		0xAE,               // Display OFF
+		0xB3, opts.clock(), // Clock divider and oscillator frequency
+		0xCA, byte(opts.H-1), // MUX ratio
+		0xA2, 0x00, // Display offset
+		0xA1, 0x00, // Start line
+	)
+
+	// Remap settings: adjust for rotation and mirroring
+	remap1, remap2 := ComputeRemap(*opts)
+	cmds = append(cmds, 0xA0, remap1, remap2) // Remap and dual COM mode
+	remapEnd = len(cmds)
 
 	cmds = append(cmds,
-		0xA0, remap1, remap2, // Remap and dual COM mode
 		0xAB, 0x01, // Function selection (enable internal VDD)
 		0xB4, 0xA0, 0xFD, // VSL (display enhancement)
 		0xC1, 0xFF, // Contrast (max)
@@ -162,25 +824,97 @@ func (d *Dev) init(opts *Opts) error {
 		0xA6, // Normal display mode
 		0xA9, // Exit partial display mode
 	)
+	return cmds, unlockEnd, remapEnd
+}
 
-	if err := d.sendCommands(cmds); err != nil {
-		return err
+// ComputeRemap returns the two parameter bytes of the remap command (0xA0)
+// that init sends for the orientation and mirroring flags in opts. It does
+// not talk to any hardware, so it can be used to preview a configuration.
+func ComputeRemap(opts Opts) (byte, byte) {
+	remap1, remap2 := byte(0x14), byte(0x11)
+	if opts.Rotated != (opts.Orientation == Rotate180) {
+		remap1 = 0x06
+		remap2 = 0x11
+	}
+	if opts.MirrorX {
+		remap1 ^= 0x02 // Column address remap
+	}
+	if opts.MirrorY {
+		remap1 ^= 0x10 // COM scan direction remap
+	}
+	if opts.Sequential {
+		remap2 |= 0x01
+	}
+	if opts.SwapTopBottom {
+		remap2 |= 0x02
 	}
+	return remap1, remap2
+}
 
-	// Clear display RAM
-	if err := d.clearRAM(); err != nil {
-		return err
+// delay pauses for t using the device's clock.
+func (d *Dev) delay(t time.Duration) {
+	d.clock.sleep(t)
+}
+
+// SetMinFrameInterval sets the minimum time between the starts of two frame
+// transmits by Write, Draw and Flush. A transmit that comes too soon blocks
+// on the device clock until the interval has elapsed, which smooths the
+// update rate of unthrottled render loops. The lock is released while
+// waiting, so other methods are not held up, and the frame is sent as it is
+// once the wait ends. Zero (the default) disables pacing.
+func (d *Dev) SetMinFrameInterval(t time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.minFrameInterval = t
+}
+
+// pace records the start of a frame transmit and reports false if the
+// minimum frame interval has elapsed since the last one. Otherwise it
+// releases the lock while waiting out the interval on the device clock, so
+// other methods are not blocked meanwhile, and reports true: the caller must
+// then start over, as other goroutines may have changed the device.
+func (d *Dev) pace() bool {
+	if d.minFrameInterval <= 0 {
+		return false
 	}
+	now := d.clock.now()
+	if !d.lastFrame.IsZero() {
+		if wait := d.lastFrame.Add(d.minFrameInterval).Sub(now); wait > 0 {
+			clk := d.clock
+			d.paceWaits++
+			d.mu.Unlock()
+			clk.sleep(wait)
+			d.mu.Lock()
+			return true
+		}
+	}
+	d.lastFrame = now
+	return false
+}
 
-	// Turn display ON
-	return d.sendCommand(0xAF)
+// SetClock replaces the time source used by subsequent delays and timed
+// operations. Operations already running, such as a FadeContrast or
+// StartAutoFlush, keep the clock they started with.
+func (d *Dev) SetClock(c Clock) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clock = c
 }
 
-// clearRAM clears all pixels in the display RAM.
-func (d *Dev) clearRAM() error {
+// currentClock returns the device clock, read under the lock, for timed
+// operations that wait without holding it.
+func (d *Dev) currentClock() Clock {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.clock
+}
+
+// clearRAM queues the commands and data clearing all pixels in the display
+// RAM on seq.
+func (d *Dev) clearRAM(seq *sequencer) error {
 	// Set column address window
-	colStart := byte(d.columnOffset / 2)
-	colEnd := byte((d.columnOffset + d.rect.Dx() - 1) / 2)
+	colStart := d.ramColumn(0)
+	colEnd := d.ramColumn(d.next.Rect.Dx() - 1)
 
 	// Set row address window
 	commands := []byte{
@@ -189,13 +923,12 @@ func (d *Dev) clearRAM() error {
 		0x5C, // Enable write to RAM
 	}
 
-	if err := d.sendCommands(commands); err != nil {
+	if err := seq.command(commands...); err != nil {
 		return err
 	}
 
 	// Send zero pixels
-	zeros := make([]byte, d.rect.Dx()*d.rect.Dy()/2)
-	return d.sendData(zeros)
+	return seq.data(make([]byte, len(d.buffer)))
 }
 
 // sendCommand sends a single command byte.
@@ -205,104 +938,568 @@ func (d *Dev) sendCommand(cmd byte) error {
 
 // sendCommands sends a slice of command bytes.
 func (d *Dev) sendCommands(cmds []byte) error {
-	if err := d.dc.Out(gpio.Low); err != nil {
-		return err
-	}
-	return d.c.Tx(cmds, nil)
+	// Any command ends the RAM write in progress
+	d.window = nil
+	d.trace(false, cmds)
+	return d.bus.SendCommand(cmds)
 }
 
 // sendData sends a slice of data bytes.
 func (d *Dev) sendData(data []byte) error {
-	if err := d.dc.Out(gpio.High); err != nil {
-		return err
+	d.trace(true, data)
+	return d.bus.SendData(data)
+}
+
+// trace reports a payload to Opts.OnCommand, if set.
+func (d *Dev) trace(isData bool, payload []byte) {
+	if d.opts.OnCommand != nil {
+		d.opts.OnCommand(isData, append([]byte(nil), payload...))
+	}
+}
+
+// sequencer queues command and data bytes and sends them in order, merging
+// consecutive bytes of the same kind into one transfer so the DC pin only
+// changes between commands and data.
+type sequencer struct {
+	d   *Dev
+	dc  gpio.Level // Kind of the queued bytes (Low for commands)
+	buf []byte
+}
+
+// command queues command bytes.
+func (s *sequencer) command(cmds ...byte) error {
+	return s.queue(gpio.Low, cmds)
+}
+
+// data queues data bytes.
+func (s *sequencer) data(data []byte) error {
+	return s.queue(gpio.High, data)
+}
+
+// queue appends b, first sending the queued bytes if they are of the other
+// kind.
+func (s *sequencer) queue(dc gpio.Level, b []byte) error {
+	if len(s.buf) > 0 && dc != s.dc {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	s.dc = dc
+	s.buf = append(s.buf, b...)
+	return nil
+}
+
+// flush sends the queued bytes, if any.
+func (s *sequencer) flush() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	var err error
+	if s.dc == gpio.High {
+		err = s.d.sendData(s.buf)
+	} else {
+		err = s.d.sendCommands(s.buf)
 	}
-	return d.c.Tx(data, nil)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// ramColumn returns the RAM column address for display column x, including
+// the centering and pan offsets.
+func (d *Dev) ramColumn(x int) byte {
+	return byte((x + d.columnOffset + d.panOffset) / 2)
 }
 
 // writeRect writes pixel data to a rectangular region of the display.
 func (d *Dev) writeRect(x, y, width, height int, pixels []byte) error {
+	// The window in whole bytes, mirrored if a software flip is enabled
+	written := image.Rect(x&^1, y, x+width+(x+width)&1, y+height)
+	window, data := d.flipRect(written), d.ramData(pixels, height)
+
 	// Calculate column addresses (in nibbles)
-	colStart := byte((x + d.columnOffset) / 2)
-	colEnd := byte((x + width - 1 + d.columnOffset) / 2)
+	colStart := d.ramColumn(window.Min.X)
+	colEnd := d.ramColumn(window.Max.X - 1)
 
 	// Set addressing window and enable RAM write
 	commands := []byte{
 		0x15, colStart, colEnd, // Column address
-		0x75, byte(y), byte(y + height - 1), // Row address
+		0x75, byte(window.Min.Y), byte(window.Max.Y - 1), // Row address
 		0x5C, // Enable write to RAM
 	}
 
-	if err := d.sendCommands(commands); err != nil {
-		return err
+	if !d.opts.ReuseWindow || !bytes.Equal(commands, d.window) {
+		if err := d.sendCommands(commands); err != nil {
+			return err
+		}
+		d.window = commands
 	}
 
 	// Send pixel data
-	return d.sendData(pixels)
+	if err := d.sendData(data); err != nil {
+		// A partial write leaves the RAM address mid-window
+		d.window = nil
+		return err
+	}
+	d.lastWrite = written
+	d.record(TransmitRecord{Rect: d.lastWrite, Bytes: len(pixels)})
+	return nil
 }
 
-// ColorModel returns the color model of the display.
-func (d *Dev) ColorModel() color.Model {
-	return image4bit.Gray4Model
+// flipRect maps a byte-aligned region of the frame buffer to the RAM window
+// it is written to, mirrored as set by Opts.SoftwareFlipH and SoftwareFlipV.
+func (d *Dev) flipRect(r image.Rectangle) image.Rectangle {
+	w, h := d.next.Rect.Dx(), d.next.Rect.Dy()
+	if d.opts.SoftwareFlipH {
+		r.Min.X, r.Max.X = w-r.Max.X, w-r.Min.X
+	}
+	if d.opts.SoftwareFlipV {
+		r.Min.Y, r.Max.Y = h-r.Max.Y, h-r.Min.Y
+	}
+	return r
+}
+
+// flipPixels mirrors pixels, made of height rows of whole bytes, to match
+// flipRect. It returns pixels unchanged if no software flip is enabled.
+func (d *Dev) flipPixels(pixels []byte, height int) []byte {
+	if !d.opts.SoftwareFlipH && !d.opts.SoftwareFlipV || height <= 0 {
+		return pixels
+	}
+	out := make([]byte, len(pixels))
+	stride := len(pixels) / height
+	for row := 0; row < height; row++ {
+		src := pixels[row*stride : (row+1)*stride]
+		dstRow := row
+		if d.opts.SoftwareFlipV {
+			dstRow = height - 1 - row
+		}
+		dst := out[dstRow*stride : (dstRow+1)*stride]
+		if !d.opts.SoftwareFlipH {
+			copy(dst, src)
+			continue
+		}
+		for i, b := range src {
+			dst[stride-1-i] = b<<4 | b>>4
+		}
+	}
+	return out
+}
+
+// ramData returns pixels, made of height rows of whole bytes, in the order
+// writeRect sends them to RAM: mirrored by flipPixels and, with
+// Opts.ColumnInterleave, interleaved by interleaveRows.
+func (d *Dev) ramData(pixels []byte, height int) []byte {
+	data := d.flipPixels(pixels, height)
+	if d.opts.ColumnInterleave {
+		data = interleaveRows(data, height)
+	}
+	return data
+}
+
+// interleaveRows returns a copy of pixels, made of height rows of equal
+// length, with the bytes at even positions of each row moved before those at
+// odd positions (see Opts.ColumnInterleave).
+func interleaveRows(pixels []byte, height int) []byte {
+	out := make([]byte, len(pixels))
+	if height <= 0 {
+		return out
+	}
+	stride := len(pixels) / height
+	half := (stride + 1) / 2
+	for row := 0; row < len(pixels); row += stride {
+		for i, b := range pixels[row : row+stride] {
+			if i%2 == 0 {
+				out[row+i/2] = b
+			} else {
+				out[row+half+i/2] = b
+			}
+		}
+	}
+	return out
+}
+
+// record adds a RAM write to the transmit history, if enabled, overwriting
+// the oldest record when full.
+func (d *Dev) record(r TransmitRecord) {
+	if cap(d.history) == 0 {
+		return
+	}
+	r.Time = d.clock.now()
+	if len(d.history) < cap(d.history) {
+		d.history = append(d.history, r)
+		return
+	}
+	d.history[d.historyNext] = r
+	d.historyNext = (d.historyNext + 1) % len(d.history)
+}
+
+// TransmitHistory returns the most recent RAM writes, oldest first, up to
+// the number set with Opts.TransmitHistory. It returns nil when the history
+// is disabled.
+func (d *Dev) TransmitHistory() []TransmitRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.history) == 0 {
+		return nil
+	}
+	out := make([]TransmitRecord, 0, len(d.history))
+	out = append(out, d.history[d.historyNext:]...)
+	return append(out, d.history[:d.historyNext]...)
+}
+
+// ColorModel returns the color model of the display.
+func (d *Dev) ColorModel() color.Model {
+	return image4bit.Gray4Model
 }
 
-// Bounds returns the image bounds of the display.
+// Bounds returns the image bounds of the display. With Opts.Orientation set
+// to Rotate90 or Rotate270 the width and height are swapped.
 func (d *Dev) Bounds() image.Rectangle {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.bounds()
+}
+
+// bounds is Bounds without locking.
+func (d *Dev) bounds() image.Rectangle {
+	if d.quarterTurn() {
+		return image.Rect(0, 0, d.rect.Dy(), d.rect.Dx())
+	}
 	return d.rect
 }
 
+// quarterTurn reports whether the orientation needs software rotation.
+func (d *Dev) quarterTurn() bool {
+	return d.opts.Orientation == Rotate90 || d.opts.Orientation == Rotate270
+}
+
+// toLogical maps a native frame buffer point to Bounds coordinates.
+func (d *Dev) toLogical(p image.Point) image.Point {
+	switch d.opts.Orientation {
+	case Rotate90:
+		return image.Pt(d.rect.Dy()-1-p.Y, p.X)
+	case Rotate270:
+		return image.Pt(p.Y, d.rect.Dx()-1-p.X)
+	}
+	return p
+}
+
+// toNativeRect maps a rectangle in Bounds coordinates to the native frame
+// buffer.
+func (d *Dev) toNativeRect(r image.Rectangle) image.Rectangle {
+	switch d.opts.Orientation {
+	case Rotate90:
+		// Native (x, y) = (ly, h - 1 - lx)
+		return image.Rect(r.Min.Y, d.rect.Dy()-r.Max.X, r.Max.Y, d.rect.Dy()-r.Min.X)
+	case Rotate270:
+		// Native (x, y) = (w - 1 - ly, lx)
+		return image.Rect(d.rect.Dx()-r.Max.Y, r.Min.X, d.rect.Dx()-r.Min.Y, r.Max.X)
+	}
+	return r
+}
+
+// rotatedImage presents an image in Bounds coordinates, offset by off, as a
+// native frame for the Rotate90 and Rotate270 orientations.
+type rotatedImage struct {
+	d   *Dev
+	src image.Image
+	off image.Point // Source point at the Bounds origin
+}
+
+func (r *rotatedImage) ColorModel() color.Model {
+	return r.src.ColorModel()
+}
+
+func (r *rotatedImage) Bounds() image.Rectangle {
+	return r.d.toNativeRect(r.src.Bounds().Sub(r.off))
+}
+
+func (r *rotatedImage) At(x, y int) color.Color {
+	p := r.d.toLogical(image.Pt(x, y)).Add(r.off)
+	return r.src.At(p.X, p.Y)
+}
+
+// drawVertical copies src into the native frame rectangle nr for the
+// Rotate90 and Rotate270 orientations, where off is the source point at the
+// Bounds origin. Both pixels of a frame byte then come from one src byte,
+// which is copied whole (nibble-swapped for Rotate270); the columns of nr
+// that only fill half a byte are drawn pixel by pixel. It reports false,
+// drawing nothing, when the byte pairs of src and of the frame do not line
+// up.
+func (d *Dev) drawVertical(nr image.Rectangle, src *image4bit.VerticalNibble, off image.Point) bool {
+	// Source row of the top pixel of the pair making up native byte bx
+	var top func(bx int) int
+	switch d.opts.Orientation {
+	case Rotate90:
+		top = func(bx int) int { return bx + off.Y }
+	case Rotate270:
+		top = func(bx int) int { return d.rect.Dx() - 2 - bx + off.Y }
+	default:
+		return false
+	}
+	if (top(0)-src.Rect.Min.Y)%2 != 0 {
+		return false
+	}
+
+	rot := &rotatedImage{d: d, src: src, off: off}
+	x0, x1 := nr.Min.X+nr.Min.X%2, nr.Max.X-nr.Max.X%2
+	if x0 >= x1 {
+		image4bit.DrawInto(d.next, nr, rot, nr.Min)
+		return true
+	}
+	if nr.Min.X < x0 {
+		r := image.Rect(nr.Min.X, nr.Min.Y, x0, nr.Max.Y)
+		image4bit.DrawInto(d.next, r, rot, r.Min)
+	}
+	if x1 < nr.Max.X {
+		r := image.Rect(x1, nr.Min.Y, nr.Max.X, nr.Max.Y)
+		image4bit.DrawInto(d.next, r, rot, r.Min)
+	}
+	for y := nr.Min.Y; y < nr.Max.Y; y++ {
+		sx := d.toLogical(image.Pt(x0, y)).X + off.X - src.Rect.Min.X
+		row := d.next.Pix[y*d.next.Stride:]
+		for bx := x0; bx < x1; bx += 2 {
+			b := src.Pix[(top(bx)-src.Rect.Min.Y)/2*src.Stride+sx]
+			if d.opts.Orientation == Rotate270 {
+				b = b<<4 | b>>4
+			}
+			row[bx/2] = b
+		}
+	}
+	return true
+}
+
 // Write writes raw pixel data to the display in HorizontalNibble format.
-// The data must be exactly d.rect.Dx() * d.rect.Dy() / 2 bytes.
+// The data must be exactly one frame: (width rounded up to even) * height / 2
+// bytes. For odd widths the padding pixel ending each row is sent as black.
 func (d *Dev) Write(pixels []byte) (int, error) {
-	if d.halted {
-		return 0, errors.New("ssd1322: halted")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(pixels)
+}
+
+// write is Write for callers already holding the lock.
+func (d *Dev) write(pixels []byte) (int, error) {
+	if err := d.ready(); err != nil {
+		return 0, err
 	}
 	if len(pixels) != len(d.buffer) {
-		return 0, errors.New("ssd1322: invalid buffer size")
+		return 0, ErrInvalidBufferSize
+	}
+	frame := pixels
+	if d.padded() {
+		frame = append([]byte(nil), pixels...)
+		d.clearPadding(frame)
 	}
-	if err := d.writeFullFrame(pixels); err != nil {
+	if d.pace() {
+		return d.write(pixels)
+	}
+	if err := d.writeFullFrame(frame); err != nil {
 		return 0, err
 	}
-	copy(d.buffer, pixels)
+	d.storeFrame(frame)
 	return len(pixels), nil
 }
 
+// ShowGradient displays a 16-step test gradient (see
+// image4bit.FillGradient) across the whole panel, left to right if
+// horizontal is true and top to bottom otherwise. It is sent as a full frame
+// like Write, which also updates the state used for differential updates.
+func (d *Dev) ShowGradient(horizontal bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	frame := image4bit.NewHorizontalNibble(d.next.Rect)
+	image4bit.FillGradient(frame, d.rect, horizontal)
+	_, err := d.write(frame.Pix)
+	return err
+}
+
+// padded reports whether the frame buffer has a padding column.
+func (d *Dev) padded() bool {
+	return d.rect.Dx()%2 != 0
+}
+
+// clearPadding blanks the padding pixel of every row of a full frame.
+func (d *Dev) clearPadding(pixels []byte) {
+	if !d.padded() {
+		return
+	}
+	stride := d.next.Stride
+	for i := stride - 1; i < len(pixels); i += stride {
+		pixels[i] &^= 0x0F
+	}
+}
+
 // Draw draws an image onto the display with differential update optimization.
 // The dst rectangle specifies the destination region on the display.
 // The src image is positioned at src point sp within the destination.
 func (d *Dev) Draw(dst image.Rectangle, src image.Image, sp image.Point) error {
-	if d.halted {
-		return errors.New("ssd1322: halted")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draw(dst, src, sp)
+}
+
+// draw is Draw for callers already holding the lock.
+func (d *Dev) draw(dst image.Rectangle, src image.Image, sp image.Point) error {
+	if err := d.ready(); err != nil {
+		return err
 	}
 
-	// Clip to display bounds
-	dst = dst.Intersect(d.rect)
-	if dst.Empty() {
+	// Clip to display bounds and to the part of src that is available,
+	// keeping sp aligned with dst.Min as draw.Draw does
+	r := dst.Intersect(d.bounds())
+	r = r.Intersect(src.Bounds().Add(dst.Min.Sub(sp)))
+	if r.Empty() {
 		return nil
 	}
+	sp = sp.Add(r.Min.Sub(dst.Min))
+	dst = r
+
+	// Rotate into the native layout, moving whole bytes for a VerticalNibble
+	// source and pixel by pixel otherwise
+	if d.quarterTurn() {
+		nr, off := d.toNativeRect(dst), sp.Sub(dst.Min)
+		if vn, ok := src.(*image4bit.VerticalNibble); !ok || !d.drawVertical(nr, vn, off) {
+			image4bit.DrawInto(d.next, nr, &rotatedImage{d: d, src: src, off: off}, nr.Min)
+		}
+		return d.flushDiff()
+	}
 
-	// Fast path: if source is already HorizontalNibble at full size
+	// Fast path: if source is already HorizontalNibble at full size, with
+	// the frame's layout, send it as a whole like Write, which also blanks
+	// the padding column
 	if srcImg, ok := src.(*image4bit.HorizontalNibble); ok {
 		zeroPoint := image.Point{}
-		if dst == d.rect && sp == zeroPoint && srcImg.Rect == d.rect {
-			return d.writeFullFrame(srcImg.Pix)
+		if dst == d.rect && sp == zeroPoint && srcImg.Rect == d.rect &&
+			srcImg.Stride == d.next.Stride && len(srcImg.Pix) >= len(d.buffer) {
+			if _, err := d.write(srcImg.Pix[:len(d.buffer)]); err != nil {
+				return err
+			}
+			d.diffRect = d.next.Rect
+			return nil
 		}
 	}
 
 	// Slow path: render to buffer with differential updates
-	// Lazy-initialize double buffer
-	if d.next == nil {
-		d.next = image4bit.NewHorizontalNibble(d.rect)
-		// Initialize last frame buffer
-		d.lastDm = image4bit.HorizontalNibble{
-			Pix:    make([]byte, len(d.buffer)),
-			Stride: d.next.Stride,
-			Rect:   d.rect,
+	// Draw source into our buffer
+	image4bit.DrawInto(d.next, dst, src, sp)
+
+	return d.flushDiff()
+}
+
+// DrawDithered draws src, aligned with its bounds' top-left corner at the
+// display origin, using Floyd-Steinberg error diffusion to approximate more
+// than 16 gray levels, then transmits the changes like Draw.
+//
+// Error diffusion is not temporally stable: re-dithering a whole video frame
+// makes static areas shimmer as errors from elsewhere ripple through them.
+// DrawDithered therefore compares src with the source of the previous call
+// and only re-dithers the bounding box of the pixels whose luminance
+// changed, leaving the rest of the frame buffer byte-identical. Errors are
+// not diffused across the edge of that box. The first call, and the first
+// after Reconfigure or after anything else changed the frame, dithers the
+// whole display.
+func (d *Dev) DrawDithered(src image.Image) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+
+	// Capture the luminance of the new source
+	if d.ditherNext == nil {
+		d.ditherNext = image.NewGray(d.rect)
+	}
+	luma := d.ditherNext
+	off := src.Bounds().Min
+	if d.quarterTurn() {
+		src, off = &rotatedImage{d: d, src: src, off: off}, image.Point{}
+	}
+	for y := 0; y < d.rect.Dy(); y++ {
+		for x := 0; x < d.rect.Dx(); x++ {
+			var v uint8
+			if p := off.Add(image.Pt(x, y)); p.In(src.Bounds()) {
+				r, g, b, _ := src.At(p.X, p.Y).RGBA()
+				v = uint8((299*r + 587*g + 114*b + 500) / 1000 >> 8)
+			}
+			luma.Pix[y*luma.Stride+x] = v
 		}
-		copy(d.lastDm.Pix, d.buffer)
 	}
 
-	// Draw source into our buffer
-	draw.Draw(d.next, dst, src, sp, draw.Src)
+	// Re-dither only where the source changed
+	changed := d.rect
+	if d.ditherPrev != nil {
+		changed = image.Rectangle{}
+		for y := 0; y < d.rect.Dy(); y++ {
+			prev := d.ditherPrev.Pix[y*luma.Stride : y*luma.Stride+d.rect.Dx()]
+			cur := luma.Pix[y*luma.Stride : y*luma.Stride+d.rect.Dx()]
+			for x := range cur {
+				if cur[x] != prev[x] {
+					changed = changed.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+	}
+	image4bit.DitherFloydSteinberg(d.next, luma.SubImage(changed))
+
+	// Storing the frame forgets the previous source, so this one is only
+	// recorded once it is on the display, and not at all if the frame could
+	// have changed while pace released the lock
+	prev, waits := d.ditherPrev, d.paceWaits
+	if err := d.flushDiff(); err != nil {
+		d.ditherPrev = nil
+		return err
+	}
+	if d.paceWaits != waits {
+		d.ditherPrev = nil
+		return nil
+	}
+	d.ditherPrev, d.ditherNext = luma, prev
+	return nil
+}
+
+// FitImage scales src to the largest size that fits the display while
+// preserving its aspect ratio, centers it with black bars on the remaining
+// sides (letterboxing), and draws it. Scaling uses bilinear interpolation.
+// If dither is true the result is drawn with DrawDithered, otherwise with
+// Draw.
+func FitImage(dev *Dev, src image.Image, dither bool) error {
+	dev.mu.Lock()
+	err := dev.ready()
+	dev.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	sb, db := src.Bounds(), dev.Bounds()
+	if sb.Empty() {
+		return errors.New("ssd1322: empty source image")
+	}
+
+	// Fit the limiting dimension, rounding the other to the nearest pixel
+	w, h := db.Dx(), db.Dy()
+	if sb.Dx()*db.Dy() > sb.Dy()*db.Dx() {
+		h = max(1, (sb.Dy()*db.Dx()+sb.Dx()/2)/sb.Dx())
+	} else {
+		w = max(1, (sb.Dx()*db.Dy()+sb.Dy()/2)/sb.Dy())
+	}
+	target := image.Rect(0, 0, w, h).Add(image.Pt((db.Dx()-w)/2, (db.Dy()-h)/2))
+
+	canvas := image.NewGray(db)
+	xdraw.BiLinear.Scale(canvas, target, src, sb, xdraw.Src, nil)
+	if dither {
+		return dev.DrawDithered(canvas)
+	}
+	return dev.Draw(db, canvas, image.Point{})
+}
+
+// flushDiff transmits the minimal region in which the next frame differs
+// from the last displayed one.
+func (d *Dev) flushDiff() error {
+	// Cheap pre-check: skip the per-row scan when the composed frame is
+	// identical to the displayed one
+	if bytes.Equal(d.next.Pix, d.lastDm.Pix) {
+		d.diffRect = image.Rectangle{}
+		return nil
+	}
 
 	// Calculate minimal bounding box of changed pixels
 	minCol, maxCol, minRow, maxRow := d.calculateDiff()
@@ -311,79 +1508,388 @@ func (d *Dev) Draw(dst image.Rectangle, src image.Image, sp image.Point) error {
 		return nil
 	}
 
-	// Extract changed region
-	changedData := d.extractRegion(minCol, maxCol, minRow, maxRow)
-
-	// Write to display
-	if err := d.writeRect(minCol, minRow, maxCol-minCol+1, maxRow-minRow+1, changedData); err != nil {
-		return err
+	// Write each disjoint changed region, or the bounding box when there
+	// are too many of them
+	var regions []image.Rectangle
+	if d.opts.MaxDiffRegions > 1 {
+		regions = diffRegions(d.diffRuns, d.opts.MaxDiffRegions)
+	}
+	if d.pace() {
+		if err := d.ready(); err != nil {
+			return err
+		}
+		return d.flushDiff()
+	}
+	if len(regions) > 1 {
+		for _, r := range regions {
+			data := d.extractFrom(d.next.Pix, r.Min.X, r.Max.X-1, r.Min.Y, r.Max.Y-1)
+			if err := d.writeRect(r.Min.X, r.Min.Y, r.Dx(), r.Dy(), data); err != nil {
+				return err
+			}
+		}
+	} else {
+		changedData := d.extractRegion(minCol, maxCol, minRow, maxRow)
+		if err := d.writeRect(minCol, minRow, maxCol-minCol+1, maxRow-minRow+1, changedData); err != nil {
+			return err
+		}
 	}
 
 	// Update stored buffers
-	copy(d.buffer, d.next.Pix)
-	copy(d.lastDm.Pix, d.next.Pix)
+	d.storeFrame(d.next.Pix)
 
 	return nil
 }
 
+// storeFrame records pixels as the frame currently shown on the display,
+// keeping the current, next and last-displayed buffers in sync.
+// It invalidates the DrawDithered source, which no longer describes the
+// frame.
+func (d *Dev) storeFrame(pixels []byte) {
+	copy(d.buffer, pixels)
+	copy(d.next.Pix, pixels)
+	copy(d.lastDm.Pix, pixels)
+	d.ditherPrev = nil
+}
+
 // calculateDiff compares the current and next buffers to find the minimal
 // changed region. Returns (minCol, maxCol, minRow, maxRow) or (1, 0, 0, 0) if no changes.
+// The region is also kept for DirtyRect.
 func (d *Dev) calculateDiff() (minCol, maxCol, minRow, maxRow int) {
-	width := d.rect.Dx()
-	height := d.rect.Dy()
+	width := d.next.Rect.Dx()
+	height := d.next.Rect.Dy()
 	stride := width / 2
 
 	minRow = height
 	maxRow = -1
 	minCol = width
 	maxCol = -1
+	minByte, maxByte := stride, -1
 
 	// Scan row by row to find differences
+	if d.opts.RecordDiffDetails {
+		d.diffDetails = d.diffDetails[:0]
+	}
+	runs := d.opts.MaxDiffRegions > 1
+	d.diffRuns = d.diffRuns[:0]
 	for y := 0; y < height; y++ {
 		rowStart := y * stride
 		rowEnd := rowStart + stride
 
-		if !bytes.Equal(d.lastDm.Pix[rowStart:rowEnd], d.next.Pix[rowStart:rowEnd]) {
-			if y < minRow {
-				minRow = y
+		last, next := d.lastDm.Pix[rowStart:rowEnd], d.next.Pix[rowStart:rowEnd]
+		if bytes.Equal(last, next) {
+			continue
+		}
+		minRow = min(minRow, y)
+		maxRow = max(maxRow, y)
+
+		// Recording every change needs the full row, searched from one
+		// change to the next
+		if d.opts.RecordDiffDetails || runs {
+			for x := firstDiff(last, next); x < stride; x += 1 + firstDiff(last[x+1:], next[x+1:]) {
+				if d.opts.RecordDiffDetails {
+					d.diffDetails = append(d.diffDetails, ByteChange{
+						Offset: rowStart + x,
+						Old:    last[x],
+						New:    next[x],
+					})
+				}
+				if runs {
+					d.addDiffRun(y, x)
+				}
+				minByte = min(minByte, x)
+				maxByte = max(maxByte, x)
 			}
-			if y > maxRow {
-				maxRow = y
+			continue
+		}
+
+		// Otherwise only bytes outside those already known to change can
+		// widen the box, and a side that matches as a whole is skipped, so
+		// a narrow change costs little more than the row comparison; a side
+		// that differs is searched inward from its end
+		if lo := minByte; !bytes.Equal(last[:lo], next[:lo]) {
+			minByte = firstDiff(last[:lo], next[:lo])
+		}
+		maxByte = max(maxByte, minByte)
+		if hi := maxByte + 1; !bytes.Equal(last[hi:], next[hi:]) {
+			maxByte = hi + lastDiff(last[hi:], next[hi:])
+		}
+	}
+
+	// Each byte holds two pixels, so the box always spans whole bytes; the
+	// frame width is even, so it never extends past the last column
+	d.diffRect = image.Rectangle{}
+	if maxByte >= 0 {
+		minCol, maxCol = minByte*2, maxByte*2+1
+		d.diffRect = image.Rect(minCol, minRow, maxCol+1, maxRow+1)
+	}
+
+	return
+}
+
+// firstDiff returns the index of the first byte at which a and b differ, or
+// len(a) if they match; b must be at least as long as a. Equal bytes are
+// skipped eight at a time.
+func firstDiff(a, b []byte) int {
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		if x := binary.LittleEndian.Uint64(a[i:]) ^ binary.LittleEndian.Uint64(b[i:]); x != 0 {
+			return i + bits.TrailingZeros64(x)/8
+		}
+	}
+	for ; i < len(a); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return len(a)
+}
+
+// lastDiff returns the index of the last byte at which a and b differ, or
+// -1 if they match, searching from the end like firstDiff.
+func lastDiff(a, b []byte) int {
+	i := len(a)
+	for ; i >= 8; i -= 8 {
+		if x := binary.LittleEndian.Uint64(a[i-8:]) ^ binary.LittleEndian.Uint64(b[i-8:]); x != 0 {
+			return i - 1 - bits.LeadingZeros64(x)/8
+		}
+	}
+	for i--; i >= 0; i-- {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// DirtyRect returns the region the last Draw, DrawDithered or Flush without
+// marked regions found changed and transmitted, in frame buffer coordinates
+// widened to whole bytes. A full-frame Draw that skips the comparison
+// reports the whole frame. It returns an empty rectangle when nothing
+// changed.
+//
+// With a quarter-turn Orientation the region is in the panel's native
+// layout, as the frame buffer is.
+func (d *Dev) DirtyRect() image.Rectangle {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.diffRect
+}
+
+// diffGap is the number of unchanged bytes worth resending rather than
+// opening a new window: a separate write costs the seven bytes of the
+// column, row and RAM write commands.
+const diffGap = 7
+
+// diffRun is a horizontal run of changed bytes in one frame buffer row,
+// covering byte columns [x0, x1). Short unchanged gaps are included.
+type diffRun struct {
+	y, x0, x1 int
+}
+
+// addDiffRun records the changed byte at column x of row y, extending the
+// previous run when it ends on the same row less than diffGap bytes before.
+func (d *Dev) addDiffRun(y, x int) {
+	if n := len(d.diffRuns); n > 0 {
+		if r := &d.diffRuns[n-1]; r.y == y && x-r.x1 < diffGap {
+			r.x1 = x + 1
+			return
+		}
+	}
+	d.diffRuns = append(d.diffRuns, diffRun{y: y, x0: x, x1: x + 1})
+}
+
+// diffRegions merges runs, in row order, into rectangles: a run joins every
+// region reaching the row above or its own row that it overlaps, or comes
+// within diffGap bytes of, horizontally. The rectangles are returned in
+// pixel coordinates, or nil when there are more than limit of them.
+func diffRegions(runs []diffRun, limit int) []image.Rectangle {
+	var regions []image.Rectangle // In byte columns; emptied when merged away
+	var prev, cur []int           // Regions reaching the previous and current row
+	y := -2
+	for _, run := range runs {
+		if run.y != y {
+			prev, cur = cur, prev[:0]
+			if run.y != y+1 {
+				prev = prev[:0]
 			}
+			y = run.y
+		}
 
-			// Scan columns within this row for precise boundaries
-			for x := 0; x < stride; x++ {
-				if d.lastDm.Pix[rowStart+x] != d.next.Pix[rowStart+x] {
-					// Each byte represents 2 pixels
-					colStart := x * 2
-					colEnd := colStart + 1
-					if colStart < minCol {
-						minCol = colStart
-					}
-					if colEnd > maxCol {
-						maxCol = colEnd
-					}
+		r := image.Rect(run.x0, run.y, run.x1, run.y+1)
+		target := -1
+		for _, list := range [2][]int{prev, cur} {
+			for _, i := range list {
+				g := regions[i]
+				if i == target || g.Empty() || g.Min.X >= r.Max.X+diffGap || r.Min.X >= g.Max.X+diffGap {
+					continue
+				}
+				r = r.Union(g)
+				if target < 0 {
+					target = i
+				} else {
+					regions[i] = image.Rectangle{}
 				}
 			}
 		}
+		if target < 0 {
+			target = len(regions)
+			regions = append(regions, r)
+		} else {
+			regions[target] = r
+		}
+		if !slices.Contains(cur, target) {
+			cur = append(cur, target)
+		}
+	}
+
+	var out []image.Rectangle
+	for _, g := range regions {
+		if g.Empty() {
+			continue
+		}
+		if len(out) == limit {
+			return nil
+		}
+		out = append(out, image.Rect(g.Min.X*2, g.Min.Y, g.Max.X*2, g.Max.Y))
 	}
+	return out
+}
 
-	// Align to even pixel boundaries
-	if minCol%2 != 0 {
-		minCol--
+// DiffDetails returns every byte the last differential update (Draw or
+// Flush without dirty regions) found changed, in frame buffer order. It
+// returns nil unless Opts.RecordDiffDetails is set. Frames found identical
+// to the displayed one as a whole leave the previous details in place.
+func (d *Dev) DiffDetails() []ByteChange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.opts.RecordDiffDetails {
+		return nil
 	}
-	if maxCol%2 == 0 && maxCol < width-1 {
-		maxCol++
+	return append([]ByteChange(nil), d.diffDetails...)
+}
+
+// RegionBytes returns a copy of the packed pixel bytes for r from the current
+// frame buffer, in HorizontalNibble layout with r.Dx()/2 bytes per row.
+//
+// r must lie within the frame buffer (including the padding column of odd
+// widths) and start and end on an even column, since each byte holds two
+// pixels.
+func (d *Dev) RegionBytes(r image.Rectangle) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkRegion(r); err != nil {
+		return nil, err
 	}
+	return d.extractFrom(d.buffer, r.Min.X, r.Max.X-1, r.Min.Y, r.Max.Y-1), nil
+}
 
-	return
+// LastFrameChecksum returns the CRC-32 (IEEE) of the frame the driver last
+// transmitted, in HorizontalNibble layout as for Write, including the
+// padding column of odd widths. Every successful Write, Draw, Flush or
+// other transmit updates it; pending changes in Image do not. Comparing it
+// with the checksum of the frame the application meant to show detects
+// dropped or diverging updates in software, without reading the RAM back.
+func (d *Dev) LastFrameChecksum() uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return crc32.ChecksumIEEE(d.buffer)
+}
+
+// ReadRAM reads the display RAM behind r back from the controller, in the
+// same HorizontalNibble layout as RegionBytes. r must lie within the frame
+// and start and end on an even column.
+//
+// Reading requires a full-duplex SPI connection with MISO wired to the
+// panel; an error is returned otherwise, including on the bus of
+// NewParallel, which never reads. As with the controller's parallel
+// interfaces, the first byte clocked out after the read command is a dummy
+// and is dropped.
+func (d *Dev) ReadRAM(r image.Rectangle) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readRAM(r)
+}
+
+// readRAM is ReadRAM for callers already holding the lock.
+func (d *Dev) readRAM(r image.Rectangle) ([]byte, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
+	}
+	t, ok := d.bus.(*spiTransport)
+	if !ok || t.c.Duplex() != conn.Full {
+		return nil, errors.New("ssd1322: connection does not support reads")
+	}
+	if err := d.checkRegion(r); err != nil {
+		return nil, err
+	}
+
+	// Set addressing window and enable RAM read
+	commands := []byte{
+		0x15, d.ramColumn(r.Min.X), d.ramColumn(r.Max.X - 1), // Column address
+		0x75, byte(r.Min.Y), byte(r.Max.Y - 1), // Row address
+		0x5D, // Enable read from RAM
+	}
+	if err := d.sendCommands(commands); err != nil {
+		return nil, err
+	}
+
+	read := make([]byte, r.Dx()/2*r.Dy()+1)
+	if err := t.readData(read); err != nil {
+		return nil, err
+	}
+	return read[1:], nil
+}
+
+// VerifyLastWrite reads back the region of the last RAM write (see ReadRAM)
+// and reports whether it matches the frame buffer, so a transfer corrupted
+// by electrical noise can be detected and retried. It returns an error if
+// nothing has been written since init or if the connection does not support
+// reads.
+func (d *Dev) VerifyLastWrite() (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return false, err
+	}
+	r := d.lastWrite
+	if r.Empty() {
+		return false, errors.New("ssd1322: no write to verify")
+	}
+	got, err := d.readRAM(d.flipRect(r))
+	if err != nil {
+		return false, err
+	}
+	want := d.ramData(d.extractFrom(d.buffer, r.Min.X, r.Max.X-1, r.Min.Y, r.Max.Y-1), r.Dy())
+	return bytes.Equal(got, want), nil
+}
+
+// checkRegion validates a region for RegionBytes and ReadRAM.
+func (d *Dev) checkRegion(r image.Rectangle) error {
+	if r.Empty() || !r.In(d.next.Rect) {
+		return ErrInvalidBounds
+	}
+	if r.Min.X%2 != 0 || r.Dx()%2 != 0 {
+		return errors.New("ssd1322: region must start and end on an even column")
+	}
+	return nil
 }
 
 // extractRegion extracts the pixel data for a rectangular region.
 func (d *Dev) extractRegion(minCol, maxCol, minRow, maxRow int) []byte {
+	return d.extractFrom(d.next.Pix, minCol, maxCol, minRow, maxRow)
+}
+
+// extractFrom extracts the pixel data for a rectangular region of a
+// full-frame buffer in HorizontalNibble layout.
+//
+// The column range is widened to whole bytes, so an odd width never drops
+// the last column.
+func (d *Dev) extractFrom(pix []byte, minCol, maxCol, minRow, maxRow int) []byte {
+	minCol &^= 1
+	maxCol |= 1
 	width := maxCol - minCol + 1
 	height := maxRow - minRow + 1
-	stride := d.rect.Dx() / 2
+	stride := d.next.Stride
 	byteWidth := width / 2
 
 	result := make([]byte, byteWidth*height)
@@ -391,7 +1897,7 @@ func (d *Dev) extractRegion(minCol, maxCol, minRow, maxRow int) []byte {
 
 	for y := minRow; y <= maxRow; y++ {
 		srcStart := y*stride + minCol/2
-		copy(result[dstIdx:], d.next.Pix[srcStart:srcStart+byteWidth])
+		copy(result[dstIdx:], pix[srcStart:srcStart+byteWidth])
 		dstIdx += byteWidth
 	}
 
@@ -400,63 +1906,865 @@ func (d *Dev) extractRegion(minCol, maxCol, minRow, maxRow int) []byte {
 
 // writeFullFrame writes the entire frame buffer to the display.
 func (d *Dev) writeFullFrame(pixels []byte) error {
-	return d.writeRect(0, 0, d.rect.Dx(), d.rect.Dy(), pixels)
+	return d.writeRect(0, 0, d.next.Rect.Dx(), d.next.Rect.Dy(), pixels)
 }
 
-// SetContrast sets the display contrast (0-255).
-func (d *Dev) SetContrast(contrast byte) error {
-	if d.halted {
-		return errors.New("ssd1322: halted")
+// SetStartColumn pans the displayed content horizontally by offset pixels
+// relative to the centered position, then re-sends the current frame at the
+// new RAM address. Positive values move the content right.
+//
+// The SSD1322 has no display start column register: the panel always shows
+// the same RAM columns, centered by the driver's columnOffset
+// ((480 - width) / 2). SetStartColumn therefore works by moving where the
+// frame is written in the 480-column RAM, so offset must be even and keep
+// the written window (columnOffset + offset up to columnOffset + offset +
+// width) within the RAM. Content shifted outside the visible columns is not
+// shown, and stale RAM contents may become visible at the opposite edge.
+// The pan stays in effect for all subsequent writes.
+func (d *Dev) SetStartColumn(offset int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
 	}
-	return d.sendCommands([]byte{0xC1, contrast})
+	if offset%2 != 0 {
+		return errors.New("ssd1322: start column offset must be even")
+	}
+	if start := d.columnOffset + offset; start < 0 || start+d.next.Rect.Dx() > d.opts.columns() {
+		return errors.New("ssd1322: start column offset out of range")
+	}
+	d.panOffset = offset
+	return d.writeFullFrame(d.buffer)
 }
 
-// Invert inverts the display colors (black becomes white and vice versa).
-func (d *Dev) Invert(invert bool) error {
-	if d.halted {
-		return errors.New("ssd1322: halted")
-	}
-	mode := byte(0xA6) // Normal display
-	if invert {
-		mode = 0xA7 // Inverted display
+// SetBuffer replaces the pending frame (see Image) without transmitting it.
+// The data must be exactly one frame in HorizontalNibble format, as for
+// Write.
+//
+// Use Flush, optionally after MarkDirty, to send the changes to the display.
+func (d *Dev) SetBuffer(pixels []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(pixels) != len(d.buffer) {
+		return ErrInvalidBufferSize
 	}
-	return d.sendCommand(mode)
+	copy(d.next.Pix, pixels)
+	d.clearPadding(d.next.Pix)
+	d.ditherPrev = nil
+	return nil
 }
 
-// Halt powers off the display.
-// After calling Halt, the display will not respond to further commands
-// until the device is re-initialized.
-func (d *Dev) Halt() error {
-	d.halted = true
-	return d.sendCommand(0xAE) // Display OFF
+// Image returns the device-managed frame buffer that Draw composes into.
+//
+// Drawing into the returned image does not transmit anything; call Flush to
+// send the changes. This lets simple applications draw with the image4bit
+// primitives without keeping their own buffer. The image stays valid until
+// the next Reconfigure. For odd widths its bounds include the padding
+// column, which should be left black. BeginFrame returns the same image,
+// for code structured around one Flush per frame.
+func (d *Dev) Image() *image4bit.HorizontalNibble {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.next
 }
 
-// String returns a string representation of the device.
-func (d *Dev) String() string {
-	return fmt.Sprintf("ssd1322.Dev{%dx%d}", d.rect.Dx(), d.rect.Dy())
+// BeginFrame starts composing a frame and returns the frame buffer to draw
+// it into, which is the image returned by Image. Any number of draw.Draw or
+// image4bit calls on the returned image are then sent together by a single
+// Flush, which diffs the frame against the displayed one and updates the
+// stored frame as Draw does.
+//
+// The image belongs to the device: callers must not retain it across
+// frames, and should call BeginFrame again for each one. With a
+// quarter-turn Orientation it is in the panel's native layout.
+func (d *Dev) BeginFrame() *image4bit.HorizontalNibble {
+	return d.Image()
 }
 
-// ScrollSpeed defines the horizontal scroll frame rate.
-type ScrollSpeed byte
+// MarkDirty records r as changed so that the next Flush transmits it.
+//
+// The rectangle is clipped to the display and widened to whole bytes
+// (even column boundaries). Overlapping dirty rectangles are merged into
+// their union; disjoint ones are kept separate.
+func (d *Dev) MarkDirty(r image.Rectangle) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r = r.Intersect(d.rect)
+	if r.Empty() {
+		return
+	}
+	r.Min.X &^= 1
+	r.Max.X += r.Max.X & 1
+
+	// Merge with any rectangles the new one overlaps, repeating until the
+	// merged rectangle no longer overlaps anything.
+	for merged := true; merged; {
+		merged = false
+		for i, o := range d.dirty {
+			if o.Overlaps(r) {
+				r = r.Union(o)
+				d.dirty = append(d.dirty[:i], d.dirty[i+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+	d.dirty = append(d.dirty, r)
+}
 
-const (
-	// Scroll frame rates (in display refresh cycles)
-	Speed6Frames   ScrollSpeed = 0x00
-	Speed10Frames  ScrollSpeed = 0x01
-	Speed100Frames ScrollSpeed = 0x02
-	Speed200Frames ScrollSpeed = 0x03
+// Flush sends the pending frame (see Image and SetBuffer) to the display.
+//
+// If regions were recorded with MarkDirty, exactly those regions are
+// transmitted and the automatic diff is skipped; the caller is then
+// responsible for marking every region it changed, as unmarked changes are
+// not sent. They stay pending, and the next differential update (a Draw, or
+// a Flush without marked regions) sends them. Otherwise Flush transmits the
+// minimal changed region, as Draw does.
+func (d *Dev) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.flush()
+}
+
+// flush is Flush for callers already holding the lock.
+func (d *Dev) flush() error {
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if len(d.dirty) == 0 {
+		return d.flushDiff()
+	}
+	if d.pace() {
+		return d.flush()
+	}
+	for len(d.dirty) > 0 {
+		r := d.dirty[0]
+		data := d.extractFrom(d.next.Pix, r.Min.X, r.Max.X-1, r.Min.Y, r.Max.Y-1)
+		if err := d.writeRect(r.Min.X, r.Min.Y, r.Dx(), r.Dy(), data); err != nil {
+			return err
+		}
+		d.storeRegion(r)
+		d.dirty = d.dirty[1:]
+	}
+	d.dirty = nil
+	return nil
+}
+
+// storeRegion records the byte-aligned region r of the next frame as shown
+// on the display, like storeFrame does for the whole frame.
+func (d *Dev) storeRegion(r image.Rectangle) {
+	stride := d.next.Stride
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		start, end := y*stride+r.Min.X/2, y*stride+(r.Max.X+1)/2
+		copy(d.buffer[start:end], d.next.Pix[start:end])
+		copy(d.lastDm.Pix[start:end], d.next.Pix[start:end])
+	}
+	d.ditherPrev = nil
+}
+
+// Update calls fn with the device-managed frame buffer (see Image) while
+// holding the device lock, so a frame can be modified from several
+// goroutines while StartAutoFlush is running without a half-drawn frame
+// being sent. fn must not call other Dev methods, which would deadlock.
+func (d *Dev) Update(fn func(img *image4bit.HorizontalNibble)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(d.next)
+}
+
+// StartAutoFlush starts a goroutine that calls Flush every interval, waiting
+// on the device clock, so that changes made through Update are transmitted
+// on a timer rather than after every mutation. Ticks are skipped while the
+// device sleeps, so changes made meanwhile are sent on the first tick after
+// Wake. Stop it with StopAutoFlush before starting it again or halting the
+// device.
+func (d *Dev) StartAutoFlush(interval time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return errors.New("ssd1322: auto flush interval must be positive")
+	}
+	if d.autoStop != nil {
+		return errors.New("ssd1322: auto flush already running")
+	}
+	stop, done := make(chan struct{}), make(chan error, 1)
+	d.autoStop, d.autoDone = stop, done
+	clk := d.clock
+	go func() {
+		var firstErr error
+		for {
+			clk.sleep(interval)
+			select {
+			case <-stop:
+				done <- firstErr
+				return
+			default:
+			}
+			err := d.Flush()
+			if err != nil && !errors.Is(err, ErrSleeping) && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}()
+	return nil
+}
+
+// StopAutoFlush stops the goroutine started by StartAutoFlush and waits for
+// it to exit, which may take up to one interval. It returns the first error
+// a background Flush reported, if any, and nil if auto flush was not
+// running.
+func (d *Dev) StopAutoFlush() error {
+	// The goroutine needs the lock to finish its Flush, so wait without it
+	d.mu.Lock()
+	stop, done := d.autoStop, d.autoDone
+	d.autoStop, d.autoDone = nil, nil
+	d.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+	return <-done
+}
+
+// ClearRegion blanks r on the display without running a diff: the region is
+// zeroed in the frame buffers and only its rows are transmitted. r is clipped
+// to the display. Since each byte holds two pixels, an r with odd edges is
+// widened to whole bytes for the transfer, sending the neighbouring pixels
+// with their currently displayed values.
+func (d *Dev) ClearRegion(r image.Rectangle) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	r = r.Intersect(d.rect)
+	if r.Empty() {
+		return nil
+	}
+
+	// The displayed frame is zeroed first, so the widened edges are sent
+	// unchanged; any pending change in r is discarded
+	image4bit.DrawInto(&d.lastDm, r, image.Black, image.Point{})
+	image4bit.DrawInto(d.next, r, image.Black, image.Point{})
+	data := d.extractFrom(d.lastDm.Pix, r.Min.X, r.Max.X-1, r.Min.Y, r.Max.Y-1)
+	minCol, maxCol := r.Min.X&^1, (r.Max.X-1)|1
+	if err := d.writeRect(minCol, r.Min.Y, maxCol-minCol+1, r.Dy(), data); err != nil {
+		return err
+	}
+
+	start, end := r.Min.Y*d.next.Stride, r.Max.Y*d.next.Stride
+	copy(d.buffer[start:end], d.lastDm.Pix[start:end])
+	d.ditherPrev = nil
+	return nil
+}
+
+// ContrastCurve defines how SetContrast maps its input to the contrast
+// current register.
+type ContrastCurve byte
+
+const (
+	// ContrastLinear passes the input through unchanged (default).
+	ContrastLinear ContrastCurve = iota
+	// ContrastSCurve compresses both ends of the range (smoothstep), giving
+	// finer control around mid contrast.
+	ContrastSCurve
+	// ContrastLogarithmic rises quickly at low inputs and flattens towards the
+	// top, giving finer control at high contrast.
+	ContrastLogarithmic
+)
+
+// apply maps an input contrast value through the curve.
+func (c ContrastCurve) apply(v byte) byte {
+	x := float64(v) / 255
+	switch c {
+	case ContrastSCurve:
+		x = x * x * (3 - 2*x)
+	case ContrastLogarithmic:
+		x = math.Log10(1 + 9*x)
+	default:
+		return v
+	}
+	return byte(math.Round(x * 255))
+}
+
+// SetContrastCurve selects the response curve used by subsequent SetContrast
+// calls. It does not change the current contrast of the display.
+func (d *Dev) SetContrastCurve(c ContrastCurve) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.contrastCurve = c
+}
+
+// SetContrast sets the display contrast (0-255).
+// The value is mapped through the curve selected by SetContrastCurve
+// before being written to the contrast current register.
+func (d *Dev) SetContrast(contrast byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	value := d.contrastCurve.apply(contrast)
+	if err := d.sendCommands([]byte{0xC1, value}); err != nil {
+		return err
+	}
+	d.contrast = value
+	return nil
+}
+
+// SetMasterContrast sets the master contrast current control (command
+// 0xC7), which scales the current of all segments to (level+1)/16 of the
+// value set by SetContrast. level must be 0-15; init sets 15 (full
+// current). Dimming with the master current keeps the ratios between gray
+// levels better than lowering SetContrast, which suits a night mode.
+func (d *Dev) SetMasterContrast(level byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if level > 0x0F {
+		return fmt.Errorf("ssd1322: master contrast must be between 0 and 15 (got %d)", level)
+	}
+	return d.sendCommands([]byte{0xC7, level})
+}
+
+// SetClockDivider changes the display clock (command 0xB3) at runtime, e.g.
+// to move the refresh rate away from a PWM frequency that causes flicker:
+// DCLK is the oscillator divided by 2^divider (0-10), and oscFreq (0-15)
+// raises the oscillator frequency. Unlike the Opts fields, zero values are
+// sent as given. Reset and Reconfigure return to the Opts setting.
+func (d *Dev) SetClockDivider(divider, oscFreq uint8) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := checkClock(divider, oscFreq); err != nil {
+		return err
+	}
+	return d.sendCommands([]byte{0xB3, oscFreq<<4 | divider})
+}
+
+// fadeStep is the interval between contrast updates during FadeContrast.
+const fadeStep = 20 * time.Millisecond
+
+// FadeContrast ramps the contrast linearly from its current value to
+// contrast (mapped through the contrast curve) over duration, updating the
+// register about every 20ms and reaching the target when duration has
+// elapsed. Steps are scheduled against the start time on the device clock,
+// so slow transfers do not stretch the fade. A device halted or put to sleep
+// meanwhile ends the fade with ErrHalted or ErrSleeping.
+func (d *Dev) FadeContrast(contrast byte, duration time.Duration) error {
+	d.mu.Lock()
+	err := d.ready()
+	from, to := int(d.contrast), int(d.contrastCurve.apply(contrast))
+	clk := d.clock
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	steps := int(duration / fadeStep)
+	if steps < 1 {
+		steps = 1
+	}
+
+	start := clk.now()
+	for i := 1; i <= steps; i++ {
+		due := start.Add(duration * time.Duration(i) / time.Duration(steps))
+		if wait := due.Sub(clk.now()); wait > 0 {
+			clk.sleep(wait)
+		}
+		if err := d.stepContrast(byte(from + (to-from)*i/steps)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stepContrast sets the contrast register to value, bypassing the contrast
+// curve, for the steps of FadeContrast and CalibrateContrast. It takes the
+// lock for each step only, so other calls can run between steps, and fails
+// if one of them halted the device or put it to sleep.
+func (d *Dev) stepContrast(value byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.sendCommands([]byte{0xC1, value}); err != nil {
+		return err
+	}
+	d.contrast = value
+	return nil
+}
+
+// Contrast calibration sweep parameters (see CalibrateContrast).
+const (
+	calibStep = 8                      // Contrast increment between steps
+	calibHold = 250 * time.Millisecond // Time each step is shown
 )
 
+// SetCalibrationSelector sets the function CalibrateContrast uses to pick the
+// best contrast. It is called once per sweep step, after the step is shown,
+// with the SetContrast value being displayed, and returns true to select it.
+// It may wait for an operator's button press or sample a light sensor.
+func (d *Dev) SetCalibrationSelector(fn func(contrast byte) bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calibSelect = fn
+}
+
+// CalibrateContrast helps find the contrast at which all 16 gray levels are
+// distinguishable. It shows a horizontal 16-step gradient (see ShowGradient)
+// and sweeps the contrast upwards from 0 in steps of 8, holding each step
+// for 250ms on the device clock, until the selector set with
+// SetCalibrationSelector accepts one. It returns the accepted value, which
+// can be passed to SetContrast, and leaves the display at that contrast.
+//
+// If no step is accepted, or ctx is cancelled, the previous contrast is
+// restored and an error is returned. The gradient stays on the display.
+func (d *Dev) CalibrateContrast(ctx context.Context) (byte, error) {
+	d.mu.Lock()
+	err := d.ready()
+	selectFn, prev, clk := d.calibSelect, d.contrast, d.clock
+	d.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if selectFn == nil {
+		return 0, errors.New("ssd1322: no calibration selector set")
+	}
+	if err := d.ShowGradient(true); err != nil {
+		return 0, err
+	}
+
+	restore := func(err error) (byte, error) {
+		if rerr := d.stepContrast(prev); rerr != nil {
+			return 0, rerr
+		}
+		return 0, err
+	}
+
+	start := clk.now()
+	for i, c := 0, 0; c <= 0xFF; i, c = i+1, c+calibStep {
+		if err := ctx.Err(); err != nil {
+			return restore(err)
+		}
+		if err := d.SetContrast(byte(c)); err != nil {
+			return 0, err
+		}
+		if wait := start.Add(time.Duration(i+1) * calibHold).Sub(clk.now()); wait > 0 {
+			clk.sleep(wait)
+		}
+		if selectFn(byte(c)) {
+			return byte(c), nil
+		}
+	}
+	return restore(errors.New("ssd1322: no contrast selected"))
+}
+
+// PrechargeProfile describes the pixel precharge timing, in display clock
+// cycles (DCLKs). The init sequence uses {Phase1: 5, Phase2: 14,
+// SecondPrecharge: 8}.
+type PrechargeProfile struct {
+	Phase1          int // Reset phase, odd from 5 to 31
+	Phase2          int // First precharge phase, 3 to 15
+	SecondPrecharge int // Second precharge period, 1 to 15
+}
+
+// registers returns the phase length (0xB1) and second precharge period
+// (0xB6) register values for the profile, validating it.
+func (p PrechargeProfile) registers() (phase, second byte, err error) {
+	if p.Phase1 < 5 || p.Phase1 > 31 || p.Phase1%2 == 0 {
+		return 0, 0, errors.New("ssd1322: phase 1 must be odd and between 5 and 31 DCLKs")
+	}
+	if p.Phase2 < 3 || p.Phase2 > 15 {
+		return 0, 0, errors.New("ssd1322: phase 2 must be between 3 and 15 DCLKs")
+	}
+	if p.SecondPrecharge < 1 || p.SecondPrecharge > 15 {
+		return 0, 0, errors.New("ssd1322: second precharge must be between 1 and 15 DCLKs")
+	}
+	if p.SecondPrecharge > p.Phase2 {
+		return 0, 0, errors.New("ssd1322: second precharge must not be longer than phase 2")
+	}
+	return byte(p.Phase2<<4 | (p.Phase1-1)/2), byte(p.SecondPrecharge), nil
+}
+
+// SetPrechargeProfile sets the phase length (command 0xB1) and second
+// precharge period (command 0xB6) together from one profile, so the two
+// registers cannot be left inconsistent.
+//
+// Besides the range of each field, the profile is rejected if the second
+// precharge is longer than phase 2: the second precharge then dominates the
+// pixel charge time, the mismatch that most often shows up as flicker.
+// Nothing is sent if the profile is invalid.
+func (d *Dev) SetPrechargeProfile(p PrechargeProfile) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	phase, second, err := p.registers()
+	if err != nil {
+		return err
+	}
+	return d.sendCommands([]byte{0xB1, phase, 0xB6, second})
+}
+
+// AlertStyle switches a high-visibility alert style (inverted display at
+// maximum contrast) on or off.
+//
+// The invert and contrast commands are sent back-to-back in a single
+// transfer so no intermediate frame is shown. Turning the style on saves the
+// current inversion and contrast, and turning it off restores them.
+func (d *Dev) AlertStyle(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if on == d.alert {
+		return nil
+	}
+	if on {
+		if err := d.sendCommands([]byte{0xA7, 0xC1, 0xFF}); err != nil {
+			return err
+		}
+		d.alertInverted, d.alertContrast = d.inverted, d.contrast
+		d.inverted, d.contrast, d.alert = true, 0xFF, true
+		return nil
+	}
+	mode := byte(0xA6) // Normal display
+	if d.alertInverted {
+		mode = 0xA7 // Inverted display
+	}
+	if err := d.sendCommands([]byte{mode, 0xC1, d.alertContrast}); err != nil {
+		return err
+	}
+	d.inverted, d.contrast, d.alert = d.alertInverted, d.alertContrast, false
+	return nil
+}
+
+// maxGrayPulse is the largest gray scale pulse width accepted by command
+// 0xB8, in DCLKs.
+const maxGrayPulse = 180
+
+// SetGrayscaleTable loads a custom grayscale table (command 0xB8) and
+// enables it (command 0x00), for gamma correction in hardware. levels holds
+// the pulse widths of GS1 to GS15 in DCLKs; GS0 is fixed at 0 by the
+// controller. As the datasheet requires, the values must be strictly
+// increasing and at most 180. Nothing is sent if the table is invalid.
+func (d *Dev) SetGrayscaleTable(levels [15]byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	for i, v := range levels {
+		if v > maxGrayPulse {
+			return fmt.Errorf("ssd1322: grayscale level GS%d is %d, above the maximum of %d", i+1, v, maxGrayPulse)
+		}
+		if i > 0 && v <= levels[i-1] {
+			return fmt.Errorf("ssd1322: grayscale table must be strictly increasing (GS%d=%d, GS%d=%d)", i, levels[i-1], i+1, v)
+		}
+	}
+	cmds := append([]byte{0xB8}, levels[:]...)
+	cmds = append(cmds, 0x00) // Enable the custom table
+	if err := d.sendCommands(cmds); err != nil {
+		return err
+	}
+	d.grayTable = append([]byte(nil), levels[:]...)
+	d.grayCustom = true
+	return nil
+}
+
+// ResetGrayscaleTable restores the controller's default linear grayscale
+// table (command 0xB9) and forgets the custom table, so it can no longer be
+// re-enabled with EnableGrayscaleTable. Use UseDefaultGrayscale to switch
+// to the default table while keeping the custom one.
+func (d *Dev) ResetGrayscaleTable() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.useDefaultGrayscale(); err != nil {
+		return err
+	}
+	d.grayTable = nil
+	return nil
+}
+
+// UseDefaultGrayscale selects the controller's built-in linear grayscale
+// table (command 0xB9). A previously set custom table is kept and can be
+// re-enabled with EnableGrayscaleTable.
+func (d *Dev) UseDefaultGrayscale() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.useDefaultGrayscale()
+}
+
+// useDefaultGrayscale is UseDefaultGrayscale for callers already holding
+// the lock.
+func (d *Dev) useDefaultGrayscale() error {
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.sendCommand(0xB9); err != nil {
+		return err
+	}
+	d.grayCustom = false
+	return nil
+}
+
+// EnableGrayscaleTable re-enables the last custom grayscale table set with
+// SetGrayscaleTable. Since UseDefaultGrayscale overwrites the controller's
+// gray scale registers, the stored values are sent again (command 0xB8)
+// before the table is enabled (command 0x00).
+func (d *Dev) EnableGrayscaleTable() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if d.grayTable == nil {
+		return errors.New("ssd1322: no custom grayscale table set")
+	}
+	if err := d.sendCommands(d.grayTableCommands()); err != nil {
+		return err
+	}
+	d.grayCustom = true
+	return nil
+}
+
+// grayTableCommands returns the commands loading and enabling the stored
+// custom grayscale table.
+func (d *Dev) grayTableCommands() []byte {
+	cmds := append([]byte{0xB8}, d.grayTable...)
+	return append(cmds, 0x00) // Enable the custom table
+}
+
+// CustomGrayscaleActive reports whether the custom grayscale table is in use
+// rather than the controller's default table.
+func (d *Dev) CustomGrayscaleActive() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.grayCustom
+}
+
+// distinguishThreshold is the smallest effective luminance difference, as a
+// fraction of full brightness, that Distinguishable treats as visible.
+const distinguishThreshold = 0.02
+
+// Distinguishable reports whether gray levels a and b are likely to look
+// different on the panel with the current contrast and grayscale table.
+//
+// The model is deliberately simple: the effective luminance of a level is
+// the contrast register value divided by 255, times the level's pulse width
+// relative to GS15 (level/15 with the default linear table, or the entry of
+// the active custom table). Two levels are distinguishable when their
+// luminances differ by at least 2% of full brightness. It ignores the
+// ambient light, the panel's own response curve and display inversion, so
+// treat the result as a guide rather than a measurement.
+func (d *Dev) Distinguishable(a, b image4bit.Gray4) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	diff := d.luminance(a.Y&0x0F) - d.luminance(b.Y&0x0F)
+	return math.Abs(diff) >= distinguishThreshold
+}
+
+// luminance returns the modelled effective luminance of a gray level in
+// [0, 1] (see Distinguishable).
+func (d *Dev) luminance(level uint8) float64 {
+	pulse := float64(level) / 15
+	if d.grayCustom && len(d.grayTable) == 15 && d.grayTable[14] > 0 {
+		pulse = 0
+		if level > 0 {
+			pulse = float64(d.grayTable[level-1]) / float64(d.grayTable[14])
+		}
+	}
+	return float64(d.contrast) / 255 * pulse
+}
+
+// Invert inverts the display colors (black becomes white and vice versa).
+func (d *Dev) Invert(invert bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	mode := byte(0xA6) // Normal display
+	if invert {
+		mode = 0xA7 // Inverted display
+	}
+	if err := d.sendCommand(mode); err != nil {
+		return err
+	}
+	d.inverted = invert
+	return nil
+}
+
+// SetRotated switches the 180° rotation (Opts.Rotated) at runtime, e.g. to
+// follow how a handheld device is held, without re-initializing or
+// clearing the display. It re-sends the remap command (0xA0) computed as
+// init does, keeping the other mirroring flags and Orientation, together
+// with the display start line (0xA1) so scrolled content stays in place.
+//
+// The COM scan direction changes immediately, while the column remap only
+// applies to data written afterwards, so SetRotated then re-sends the
+// current frame. Until that transfer completes the panel may briefly show
+// the old frame mirrored.
+func (d *Dev) SetRotated(rotated bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	opts := d.opts
+	opts.Rotated = rotated
+	remap1, remap2 := ComputeRemap(opts)
+	if err := d.sendCommands([]byte{0xA0, remap1, remap2, 0xA1, d.startLine}); err != nil {
+		return err
+	}
+	d.opts.Rotated = rotated
+	return d.writeFullFrame(d.buffer)
+}
+
+// Halt powers off the display.
+// After calling Halt, the display will not respond to further commands
+// until the device is re-initialized with Reset.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.halted = true
+	return d.sendCommand(0xAE) // Display OFF
+}
+
+// Sleep turns the display off (command 0xAE) for low-power standby while
+// keeping the display RAM and all buffers, so Wake shows the same content
+// again without re-initializing. Until Wake, every other method that talks
+// to the display fails with ErrSleeping, except that a running auto-flush
+// (see StartAutoFlush) quietly skips its ticks; Halt and Reset still work.
+// Sleeping again has no effect.
+func (d *Dev) Sleep() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sleeping && d.initialized && !d.halted {
+		return nil
+	}
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.sendCommand(0xAE); err != nil { // Display OFF
+		return err
+	}
+	d.sleeping = true
+	return nil
+}
+
+// Wake turns the display back on (command 0xAF) after Sleep, showing the
+// content it had. It has no effect if the device is not sleeping.
+func (d *Dev) Wake() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.initialized || d.halted || !d.sleeping {
+		return d.ready()
+	}
+	if err := d.sendCommand(0xAF); err != nil { // Display ON
+		return err
+	}
+	d.sleeping = false
+	return nil
+}
+
+// Reset wakes the display after Halt, or retries a failed initialization:
+// it runs the hardware reset sequence (if Opts.RST is set) and the full
+// initialization sequence again, turning the display back on.
+//
+// The display RAM and the frame buffers used by Write, Draw and Image are
+// cleared in place, so images returned by Image and BeginFrame stay valid,
+// and the differential update state is reset to match, so the next frame
+// is compared against a blank screen. Contrast, inversion, scrolling, the
+// start line and the grayscale table selection return to their initial
+// settings. If the initialization fails the device is left
+// uninitialized, as with Reconfigure.
+func (d *Dev) Reset() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clearFrames()
+	d.halted = false
+	return d.init(&d.opts)
+}
+
+// String returns a string representation of the device.
+func (d *Dev) String() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return fmt.Sprintf("ssd1322.Dev{%dx%d}", d.rect.Dx(), d.rect.Dy())
+}
+
+// ScrollSpeed defines the horizontal scroll frame rate.
+type ScrollSpeed byte
+
+const (
+	// Scroll frame rates (in display refresh cycles)
+	Speed6Frames   ScrollSpeed = 0x00
+	Speed10Frames  ScrollSpeed = 0x01
+	Speed100Frames ScrollSpeed = 0x02
+	Speed200Frames ScrollSpeed = 0x03
+)
+
+// nominalFrameRate is the approximate display refresh rate, in Hz, with the
+// oscillator and phase settings used by the init sequence.
+const nominalFrameRate = 100
+
+// scrollIntervals maps each ScrollSpeed to its step interval in frames.
+var scrollIntervals = [...]int{
+	Speed6Frames:   6,
+	Speed10Frames:  10,
+	Speed100Frames: 100,
+	Speed200Frames: 200,
+}
+
+// ScrollSpeedForFPS returns the ScrollSpeed whose step rate is closest to
+// fps scroll steps per second.
+//
+// The mapping assumes the nominal ~100Hz refresh rate of the default init
+// sequence, so the supported rates are roughly 16.7, 10, 1 and 0.5 steps per
+// second; the actual rate depends on the panel's oscillator.
+func ScrollSpeedForFPS(fps int) (ScrollSpeed, error) {
+	if fps <= 0 {
+		return 0, errors.New("ssd1322: scroll rate must be positive")
+	}
+	best, bestDist := Speed6Frames, math.Inf(1)
+	for s, frames := range scrollIntervals {
+		rate := float64(nominalFrameRate) / float64(frames)
+		if dist := math.Abs(math.Log(rate / float64(fps))); dist < bestDist {
+			best, bestDist = ScrollSpeed(s), dist
+		}
+	}
+	return best, nil
+}
+
 // ScrollHorizontal starts horizontal scrolling on the display.
 // startRow and endRow specify the scroll region (must be >= 0 and < height).
 // If right is true, scrolls right; otherwise scrolls left.
 func (d *Dev) ScrollHorizontal(startRow, endRow byte, speed ScrollSpeed, right bool) error {
-	if d.halted {
-		return errors.New("ssd1322: halted")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
 	}
 
 	if int(startRow) >= d.rect.Dy() || int(endRow) >= d.rect.Dy() {
-		return errors.New("ssd1322: scroll row out of range")
+		return ErrScrollRowOutOfRange
+	}
+	if int(speed) >= len(scrollIntervals) {
+		return errors.New("ssd1322: invalid scroll speed")
+	}
+	if d.partial != nil {
+		return errPartialScroll
 	}
 
 	// Select scroll direction command
@@ -466,7 +2774,7 @@ func (d *Dev) ScrollHorizontal(startRow, endRow byte, speed ScrollSpeed, right b
 	}
 
 	// Send scroll setup command
-	return d.sendCommands([]byte{
+	if err := d.sendCommands([]byte{
 		scrollCmd,
 		0x00,        // Dummy byte (always 0x00)
 		startRow,    // Start row
@@ -474,13 +2782,463 @@ func (d *Dev) ScrollHorizontal(startRow, endRow byte, speed ScrollSpeed, right b
 		endRow,      // End row
 		0x00, 0x00,  // Dummy bytes
 		0x2F, // Activate scroll
-	})
+	}); err != nil {
+		return err
+	}
+	d.scroll = &ScrollState{StartRow: startRow, EndRow: endRow, Speed: speed, Right: right}
+	return nil
 }
 
 // StopScroll stops all scrolling and resets the display to normal operation.
 func (d *Dev) StopScroll() error {
-	if d.halted {
-		return errors.New("ssd1322: halted")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.sendCommand(0x2E); err != nil { // Deactivate scroll
+		return err
+	}
+	d.scroll = nil
+	return nil
+}
+
+// errPartialScroll is returned when scrolling and the partial display
+// window would be combined, which the datasheet does not allow.
+var errPartialScroll = errors.New("ssd1322: scrolling and partial display cannot be combined")
+
+// SetPartialDisplay powers only display rows startRow to endRow (inclusive)
+// with command 0xA8, leaving the rows outside the window dark to save
+// energy on always-on status displays. Unlike a partial RAM update, this
+// changes which rows the panel drives; the RAM and frame buffers keep their
+// content, which reappears after ExitPartialDisplay.
+//
+// The datasheet does not allow partial display while scrolling, so
+// SetPartialDisplay fails while a horizontal scroll is active (see
+// StopScroll), and ScrollHorizontal fails while a window is set.
+func (d *Dev) SetPartialDisplay(startRow, endRow byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if int(endRow) >= d.rect.Dy() {
+		return errors.New("ssd1322: partial display row out of range")
+	}
+	if startRow > endRow {
+		return errors.New("ssd1322: partial display start row after end row")
+	}
+	if d.scroll != nil {
+		return errPartialScroll
+	}
+	if err := d.sendCommands([]byte{0xA8, startRow, endRow}); err != nil {
+		return err
+	}
+	d.partial = &PartialWindow{StartRow: startRow, EndRow: endRow}
+	return nil
+}
+
+// ExitPartialDisplay powers all rows again (command 0xA9) after
+// SetPartialDisplay.
+func (d *Dev) ExitPartialDisplay() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.sendCommand(0xA9); err != nil {
+		return err
+	}
+	d.partial = nil
+	return nil
+}
+
+// ScrollState describes a horizontal scroll started with ScrollHorizontal.
+type ScrollState struct {
+	StartRow, EndRow byte
+	Speed            ScrollSpeed
+	Right            bool
+}
+
+// PartialWindow describes the rows powered by SetPartialDisplay.
+type PartialWindow struct {
+	StartRow, EndRow byte
+}
+
+// DevState is a snapshot of the display settings taken by SaveState.
+type DevState struct {
+	Contrast        byte           // Contrast register value
+	Inverted        bool           // Whether the display is inverted
+	StartLine       byte           // RAM row shown at the top of the panel
+	Scroll          *ScrollState   // Active horizontal scroll (nil if stopped)
+	Partial         *PartialWindow // Partial display window (nil if off)
+	CustomGrayscale bool           // Whether the custom grayscale table is active
+
+	// AlertStyle state, so a snapshot taken during an alert can still turn
+	// it off afterwards
+	alert         bool
+	alertInverted bool
+	alertContrast byte
+}
+
+// SaveState returns the current contrast, inversion, start line, scroll,
+// partial display window and grayscale table selection, for RestoreState to
+// re-apply after a transient effect.
+func (d *Dev) SaveState() DevState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := DevState{
+		Contrast:        d.contrast,
+		Inverted:        d.inverted,
+		StartLine:       d.startLine,
+		CustomGrayscale: d.grayCustom,
+		alert:           d.alert,
+		alertInverted:   d.alertInverted,
+		alertContrast:   d.alertContrast,
+	}
+	if d.scroll != nil {
+		scroll := *d.scroll
+		s.Scroll = &scroll
+	}
+	if d.partial != nil {
+		partial := *d.partial
+		s.Partial = &partial
+	}
+	return s
+}
+
+// RestoreState re-applies a snapshot taken by SaveState. All settings are
+// sent in a single transfer, so no mix of old and new settings is shown. A
+// running scroll is stopped first and the snapshot's scroll, if any, is
+// started again; likewise a partial display window is left with command
+// 0xA9 or set again with 0xA8 to match the snapshot. The custom grayscale
+// table is re-sent and enabled from the last table set; the table values
+// themselves are not part of the snapshot. Nothing is sent if the snapshot
+// cannot be applied.
+func (d *Dev) RestoreState(s DevState) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if s.StartLine >= ramRows {
+		return errors.New("ssd1322: start line out of range")
+	}
+	if s.CustomGrayscale && d.grayTable == nil {
+		return errors.New("ssd1322: no custom grayscale table set")
+	}
+	if sc := s.Scroll; sc != nil {
+		if int(sc.StartRow) >= d.rect.Dy() || int(sc.EndRow) >= d.rect.Dy() {
+			return ErrScrollRowOutOfRange
+		}
+		if int(sc.Speed) >= len(scrollIntervals) {
+			return errors.New("ssd1322: invalid scroll speed")
+		}
+		if s.Partial != nil {
+			return errPartialScroll
+		}
+	}
+	if pw := s.Partial; pw != nil {
+		if int(pw.EndRow) >= d.rect.Dy() {
+			return errors.New("ssd1322: partial display row out of range")
+		}
+		if pw.StartRow > pw.EndRow {
+			return errors.New("ssd1322: partial display start row after end row")
+		}
+	}
+
+	var cmds []byte
+	if d.scroll != nil {
+		cmds = append(cmds, 0x2E) // Deactivate scroll
+	}
+	if d.partial != nil && s.Partial == nil {
+		cmds = append(cmds, 0xA9) // Exit partial display mode
+	}
+	mode := byte(0xA6) // Normal display
+	if s.Inverted {
+		mode = 0xA7 // Inverted display
+	}
+	cmds = append(cmds, mode, 0xC1, s.Contrast, 0xA1, s.StartLine)
+	if s.CustomGrayscale {
+		cmds = append(cmds, d.grayTableCommands()...)
+	} else {
+		cmds = append(cmds, 0xB9) // Default grayscale table
+	}
+	if pw := s.Partial; pw != nil {
+		cmds = append(cmds, 0xA8, pw.StartRow, pw.EndRow)
+	}
+	if sc := s.Scroll; sc != nil {
+		scrollCmd := byte(0x26) // Left
+		if sc.Right {
+			scrollCmd = 0x27 // Right
+		}
+		cmds = append(cmds, scrollCmd, 0x00, sc.StartRow, byte(sc.Speed), sc.EndRow, 0x00, 0x00, 0x2F)
+	}
+	if err := d.sendCommands(cmds); err != nil {
+		return err
+	}
+
+	d.contrast, d.inverted, d.startLine, d.grayCustom = s.Contrast, s.Inverted, s.StartLine, s.CustomGrayscale
+	d.alert, d.alertInverted, d.alertContrast = s.alert, s.alertInverted, s.alertContrast
+	d.scroll = nil
+	if s.Scroll != nil {
+		scroll := *s.Scroll
+		d.scroll = &scroll
+	}
+	d.partial = nil
+	if s.Partial != nil {
+		partial := *s.Partial
+		d.partial = &partial
+	}
+	return nil
+}
+
+// ramRows is the number of rows in the controller's display RAM.
+const ramRows = 128
+
+// SetStartLine selects the RAM row shown at the top of the panel (command
+// 0xA1), panning the display vertically in hardware; rows past the end of
+// the 128-row RAM wrap around to row 0. line must be below 128.
+//
+// Together with content written below the visible rows, this implements a
+// scroll buffer: moving the start line pans over the stored content
+// without re-sending any pixel data (see also ScrollContentVertical). The
+// frame buffers are not moved, so Draw and Write keep addressing RAM from
+// row 0.
+func (d *Dev) SetStartLine(line byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if line >= ramRows {
+		return fmt.Errorf("ssd1322: start line must be below %d (got %d)", ramRows, line)
+	}
+	return d.setStartLine(line)
+}
+
+// SetDisplayOffset shifts the mapping of display rows to COM outputs by
+// offset rows (command 0xA2, vertical scroll by COM), e.g. to align two
+// panels whose first physical row differs. offset must be below 128; init
+// sets 0.
+//
+// Unlike SetStartLine, which moves the RAM row read for the top of the
+// panel, the offset moves where the scan starts on the COM pins, so it
+// compensates for the panel's wiring rather than panning the content. The
+// frame buffers and RAM addressing are unaffected.
+func (d *Dev) SetDisplayOffset(offset byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if offset >= ramRows {
+		return fmt.Errorf("ssd1322: display offset must be below %d (got %d)", ramRows, offset)
+	}
+	return d.sendCommands([]byte{0xA2, offset})
+}
+
+// setStartLine selects the RAM row shown at the top of the panel (command
+// 0xA1). line must be below ramRows.
+func (d *Dev) setStartLine(line byte) error {
+	if err := d.sendCommands([]byte{0xA1, line}); err != nil {
+		return err
+	}
+	d.startLine = line
+	return nil
+}
+
+// ScrollContentVertical scrolls the content held in display RAM upwards by
+// moving the display start line one row at a time, pixelsPerSecond rows per
+// second, wrapping around the 128 RAM rows. Content taller than the panel
+// can be written below the visible rows beforehand, for a credits-style
+// scroll that only costs one command per row.
+//
+// It runs from the current start line until ctx is cancelled and then
+// returns ctx.Err(). Steps are timed with the device clock and scheduled
+// against the start time, so slow transfers do not slow the scroll;
+// cancellation is noticed between steps.
+func (d *Dev) ScrollContentVertical(ctx context.Context, pixelsPerSecond int) error {
+	d.mu.Lock()
+	err := d.ready()
+	clk := d.clock
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if pixelsPerSecond <= 0 {
+		return errors.New("ssd1322: scroll rate must be positive")
+	}
+
+	interval := time.Second / time.Duration(pixelsPerSecond)
+	start := clk.now()
+	for i := 1; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if wait := start.Add(time.Duration(i) * interval).Sub(clk.now()); wait > 0 {
+			clk.sleep(wait)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		d.mu.Lock()
+		err := d.setStartLine((d.startLine + 1) % ramRows)
+		d.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ScrollVertical scrolls src upwards through the display in software, for
+// tickers taller than the panel: every interval it draws the display-sized
+// window of src starting step rows further down, wrapping around from the
+// bottom of src to its top. Each frame goes through Draw, so only the rows
+// that differ from the previous frame are transmitted; sparse content such
+// as text on a black background costs far less than full frames.
+//
+// src must be at least as tall as the display and is aligned with its
+// top-left corner at the display origin. ScrollVertical draws the first
+// window immediately and runs until ctx is cancelled, then returns
+// ctx.Err(). Frames are timed with the device clock and scheduled against
+// the start time, like ScrollContentVertical.
+func (d *Dev) ScrollVertical(ctx context.Context, src image.Image, step int, interval time.Duration) error {
+	if step <= 0 {
+		return errors.New("ssd1322: scroll step must be positive")
+	}
+	if interval <= 0 {
+		return errors.New("ssd1322: scroll interval must be positive")
+	}
+	b := d.Bounds()
+	if src.Bounds().Dy() < b.Dy() {
+		return errors.New("ssd1322: scroll source shorter than the display")
+	}
+
+	window := &scrollWindow{src: src, r: b}
+	clk := d.currentClock()
+	start := clk.now()
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if wait := start.Add(time.Duration(i) * interval).Sub(clk.now()); wait > 0 {
+			clk.sleep(wait)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		window.off = i * step % src.Bounds().Dy()
+		if err := d.Draw(b, window, b.Min); err != nil {
+			return err
+		}
+	}
+}
+
+// scrollWindow is the part of src shown by ScrollVertical: the rows from off
+// downwards, wrapping around the bottom of src, presented at bounds r.
+type scrollWindow struct {
+	src image.Image
+	r   image.Rectangle
+	off int
+}
+
+func (w *scrollWindow) ColorModel() color.Model { return w.src.ColorModel() }
+
+func (w *scrollWindow) Bounds() image.Rectangle { return w.r }
+
+func (w *scrollWindow) At(x, y int) color.Color {
+	sb := w.src.Bounds()
+	return w.src.At(sb.Min.X+x-w.r.Min.X, sb.Min.Y+(y-w.r.Min.Y+w.off)%sb.Dy())
+}
+
+// PlayGIF plays the animation g with its top-left corner at the display
+// origin. Frames are composited onto a canvas the size of the GIF's logical
+// screen the way browsers do: transparent pixels keep what earlier frames
+// left, and each frame's disposal method is applied before the next one,
+// with DisposalBackground clearing the frame's area to black. Colors are
+// converted with the display's color model, and every canvas goes through
+// Draw, so only what changed between frames is transmitted.
+//
+// Each frame is shown for its delay, timed with the device clock and
+// scheduled against the start time like ScrollVertical. The animation
+// repeats as g.LoopCount says, forever when it is 0, starting from a black
+// canvas each time. PlayGIF returns nil once the last frame's delay has
+// passed, or ctx.Err() when ctx is cancelled, which is checked between
+// frames.
+func (d *Dev) PlayGIF(ctx context.Context, g *gif.GIF) error {
+	if len(g.Image) == 0 {
+		return errors.New("ssd1322: GIF has no frames")
+	}
+	if len(g.Delay) != len(g.Image) {
+		return errors.New("ssd1322: GIF frame and delay counts differ")
+	}
+
+	// Files without a logical screen size get the union of their frames;
+	// an odd width gains a black column, as two pixels share a byte
+	screen := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if screen.Empty() {
+		for _, frame := range g.Image {
+			screen = screen.Union(frame.Bounds())
+		}
+	}
+	canvas := image4bit.NewHorizontalNibble(image.Rect(0, 0, (screen.Max.X+1)&^1, screen.Max.Y))
+	var saved []byte
+
+	b, clk := d.Bounds(), d.currentClock()
+	next := clk.now()
+	for played := 0; g.LoopCount == 0 || played <= max(g.LoopCount, 0); played++ {
+		canvas.Fill(image4bit.Gray4{})
+		for i, frame := range g.Image {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var disposal byte
+			if i < len(g.Disposal) {
+				disposal = g.Disposal[i]
+			}
+			if disposal == gif.DisposalPrevious {
+				saved = append(saved[:0], canvas.Pix...)
+			}
+			r := frame.Bounds().Intersect(canvas.Rect)
+			drawGIFFrame(canvas, frame, r, d.ColorModel())
+			if err := d.Draw(b, canvas, image.Point{}); err != nil {
+				return err
+			}
+
+			// Delays are in hundredths of a second
+			next = next.Add(time.Duration(g.Delay[i]) * 10 * time.Millisecond)
+			if wait := next.Sub(clk.now()); wait > 0 {
+				clk.sleep(wait)
+			}
+
+			switch disposal {
+			case gif.DisposalBackground:
+				canvas.FillRect(r, image4bit.Gray4{})
+			case gif.DisposalPrevious:
+				copy(canvas.Pix, saved)
+			}
+		}
+	}
+	return nil
+}
+
+// drawGIFFrame composites the opaque pixels of frame within r onto canvas,
+// converting each palette entry once with m.
+func drawGIFFrame(canvas *image4bit.HorizontalNibble, frame *image.Paletted, r image.Rectangle, m color.Model) {
+	var levels [256]image4bit.Gray4
+	var opaque [256]bool
+	for i, c := range frame.Palette {
+		if _, _, _, a := c.RGBA(); a != 0 {
+			levels[i] = m.Convert(c).(image4bit.Gray4)
+			opaque[i] = true
+		}
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if i := frame.ColorIndexAt(x, y); opaque[i] {
+				canvas.SetGray4(x, y, levels[i])
+			}
+		}
 	}
-	return d.sendCommand(0x2E) // Deactivate scroll
 }
@@ -1,12 +1,198 @@
 package ssd1322
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"reflect"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/flavioheleno/ssd1322/image4bit"
+	"periph.io/x/conn/v3"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/spi"
 )
 
+// fakeTx is a single transfer recorded by fakeBus.
+type fakeTx struct {
+	dc gpio.Level // DC pin level during the transfer (Low = command, High = data)
+	w  []byte
+}
+
+// fakeBus is an in-memory SPI port and DC pin that records every transfer.
+type fakeBus struct {
+	dc     gpio.Level
+	dcOuts int // Writes to the DC pin
+	txs    []fakeTx
+
+	full bool   // Report a full-duplex connection
+	read []byte // Bytes returned to the read buffer of data transfers
+	err  error  // Error returned by every transfer (nil to succeed)
+
+	freq physic.Frequency // Arguments of the last Connect
+	mode spi.Mode
+}
+
+func (b *fakeBus) String() string { return "fakeBus" }
+
+func (b *fakeBus) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, error) {
+	b.freq, b.mode = f, mode
+	return b, nil
+}
+
+func (b *fakeBus) Tx(w, r []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.txs = append(b.txs, fakeTx{dc: b.dc, w: append([]byte(nil), w...)})
+	if b.dc == gpio.High {
+		copy(r, b.read)
+	}
+	return nil
+}
+
+func (b *fakeBus) TxPackets(p []spi.Packet) error {
+	for _, pkt := range p {
+		if err := b.Tx(pkt.W, pkt.R); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *fakeBus) Duplex() conn.Duplex {
+	if b.full {
+		return conn.Full
+	}
+	return conn.Half
+}
+
+// data returns the payload of every data (DC high) transfer.
+func (b *fakeBus) data() [][]byte {
+	var out [][]byte
+	for _, tx := range b.txs {
+		if tx.dc == gpio.High {
+			out = append(out, tx.w)
+		}
+	}
+	return out
+}
+
+// transport returns the SPI transport NewSPI would create on b.
+func (b *fakeBus) transport() *spiTransport {
+	return &spiTransport{c: b, dc: dcPin{pin: &fakeDC{bus: b}}, maxTx: defaultMaxTxSize}
+}
+
+// reset forgets all recorded transfers.
+func (b *fakeBus) reset() {
+	b.txs = nil
+}
+
+// fakeDC is the DC pin of a fakeBus.
+type fakeDC struct {
+	bus *fakeBus
+}
+
+func (p *fakeDC) String() string                        { return "DC" }
+func (p *fakeDC) Halt() error                           { return nil }
+func (p *fakeDC) Name() string                          { return "DC" }
+func (p *fakeDC) Number() int                           { return -1 }
+func (p *fakeDC) Function() string                      { return "Out" }
+func (p *fakeDC) PWM(gpio.Duty, physic.Frequency) error { return nil }
+func (p *fakeDC) Out(l gpio.Level) error                { p.bus.dc = l; p.bus.dcOuts++; return nil }
+
+// fakeParallel models the 8080 parallel interface of a panel: it logs every
+// pin write and latches the data pins on each rising edge of WR while CS is
+// low.
+type fakeParallel struct {
+	data      byte
+	dc, wr    gpio.Level
+	cs        gpio.Level
+	log       []string
+	latched   []fakeTx // One transfer per latched byte
+	readPulse bool     // Whether RD was ever driven low
+}
+
+// pins returns the bus pins as passed to NewParallel.
+func (b *fakeParallel) pins() (data [8]gpio.PinOut, wr, rd, dc, cs gpio.PinOut) {
+	for i := range data {
+		data[i] = &fakeParallelPin{bus: b, name: fmt.Sprintf("D%d", i), bit: i}
+	}
+	pin := func(name string) gpio.PinOut { return &fakeParallelPin{bus: b, name: name, bit: -1} }
+	return data, pin("WR"), pin("RD"), pin("DC"), pin("CS")
+}
+
+// fakeParallelPin is a pin of a fakeParallel; bit is the data line number,
+// or -1 for the control pins.
+type fakeParallelPin struct {
+	bus  *fakeParallel
+	name string
+	bit  int
+}
+
+func (p *fakeParallelPin) String() string                        { return p.name }
+func (p *fakeParallelPin) Halt() error                           { return nil }
+func (p *fakeParallelPin) Name() string                          { return p.name }
+func (p *fakeParallelPin) Number() int                           { return -1 }
+func (p *fakeParallelPin) Function() string                      { return "Out" }
+func (p *fakeParallelPin) PWM(gpio.Duty, physic.Frequency) error { return nil }
+
+func (p *fakeParallelPin) Out(l gpio.Level) error {
+	b := p.bus
+	b.log = append(b.log, p.name+"="+l.String()[:1])
+	switch {
+	case p.bit >= 0 && l == gpio.High:
+		b.data |= 1 << p.bit
+	case p.bit >= 0:
+		b.data &^= 1 << p.bit
+	case p.name == "WR":
+		if b.wr == gpio.Low && l == gpio.High && b.cs == gpio.Low {
+			b.latched = append(b.latched, fakeTx{dc: b.dc, w: []byte{b.data}})
+		}
+		b.wr = l
+	case p.name == "DC":
+		b.dc = l
+	case p.name == "CS":
+		b.cs = l
+	case p.name == "RD":
+		b.readPulse = b.readPulse || l == gpio.Low
+	}
+	return nil
+}
+
+// bytes returns the latched bytes sent with DC at level dc.
+func (b *fakeParallel) bytes(dc gpio.Level) []byte {
+	var out []byte
+	for _, tx := range b.latched {
+		if tx.dc == dc {
+			out = append(out, tx.w...)
+		}
+	}
+	return out
+}
+
+// newTestDev creates a Dev on a fakeBus and clears the init traffic.
+func newTestDev(t *testing.T, opts *Opts) (*Dev, *fakeBus) {
+	t.Helper()
+	bus := &fakeBus{}
+	dev, err := NewSPI(bus, &fakeDC{bus: bus}, opts)
+	if err != nil {
+		t.Fatalf("NewSPI() error = %v", err)
+	}
+	bus.reset()
+	return dev, bus
+}
+
 func TestOptsValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -17,7 +203,7 @@ func TestOptsValidation(t *testing.T) {
 		{"valid 256x64", &Opts{W: 256, H: 64}, false},
 		{"valid 128x64", &Opts{W: 128, H: 64}, false},
 		{"valid 2x2 (minimum)", &Opts{W: 2, H: 1}, false},
-		{"odd width", &Opts{W: 255, H: 64}, true},
+		{"odd width (padded)", &Opts{W: 255, H: 64}, false},
 		{"width zero", &Opts{W: 0, H: 64}, true},
 		{"width > 480", &Opts{W: 512, H: 64}, true},
 		{"height zero", &Opts{W: 256, H: 0}, true},
@@ -33,7 +219,7 @@ func TestOptsValidation(t *testing.T) {
 				opts = &Opts{W: 256, H: 64}
 			}
 
-			if opts.W <= 0 || opts.W%2 != 0 || opts.W > 480 {
+			if opts.W <= 0 || opts.W > 480 {
 				if !tt.wantErr {
 					t.Error("expected error but didn't get one")
 				}
@@ -144,8 +330,9 @@ func TestDevColumnOffset(t *testing.T) {
 
 func TestWriteInvalidBufferSize(t *testing.T) {
 	dev := &Dev{
-		rect:   image.Rect(0, 0, 256, 64),
-		buffer: make([]byte, 256*64/2),
+		rect:        image.Rect(0, 0, 256, 64),
+		buffer:      make([]byte, 256*64/2),
+		initialized: true,
 	}
 
 	// Wrong buffer size should fail validation
@@ -239,6 +426,93 @@ func TestExtractRegion(t *testing.T) {
 	}
 }
 
+func TestRegionBytes(t *testing.T) {
+	dev := &Dev{
+		rect:   image.Rect(0, 0, 8, 2),
+		buffer: []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77},
+		next:   image4bit.NewHorizontalNibble(image.Rect(0, 0, 8, 2)),
+	}
+
+	// Same region as TestExtractRegion: columns 2-5, row 0
+	region, err := dev.RegionBytes(image.Rect(2, 0, 6, 1))
+	if err != nil {
+		t.Fatalf("RegionBytes() error = %v", err)
+	}
+	if want := []byte{0x11, 0x22}; !bytes.Equal(region, want) {
+		t.Errorf("RegionBytes() = %X, want %X", region, want)
+	}
+
+	// Returned slice is a copy
+	region[0] = 0xFF
+	if dev.buffer[1] != 0x11 {
+		t.Error("RegionBytes() result aliases the frame buffer")
+	}
+
+	region, err = dev.RegionBytes(image.Rect(0, 0, 4, 2))
+	if err != nil {
+		t.Fatalf("RegionBytes() error = %v", err)
+	}
+	if want := []byte{0x00, 0x11, 0x44, 0x55}; !bytes.Equal(region, want) {
+		t.Errorf("RegionBytes() = %X, want %X", region, want)
+	}
+
+	invalid := []struct {
+		name string
+		r    image.Rectangle
+	}{
+		{"odd x", image.Rect(1, 0, 3, 1)},
+		{"odd width", image.Rect(2, 0, 5, 1)},
+		{"out of bounds", image.Rect(4, 0, 10, 1)},
+		{"empty", image.Rectangle{}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := dev.RegionBytes(tt.r); err == nil {
+				t.Errorf("RegionBytes(%v) should fail", tt.r)
+			}
+		})
+	}
+}
+
+func TestExtractRegionOddWidth(t *testing.T) {
+	dev := &Dev{
+		rect: image.Rect(0, 0, 8, 1),
+		next: &image4bit.HorizontalNibble{
+			Pix:    []byte{0x01, 0x23, 0x45, 0x67},
+			Stride: 4,
+			Rect:   image.Rect(0, 0, 8, 1),
+		},
+	}
+
+	// Columns 3-6 span bytes 1-3; neither edge pixel may be dropped
+	if got, want := dev.extractRegion(3, 6, 0, 0), []byte{0x23, 0x45, 0x67}; !bytes.Equal(got, want) {
+		t.Errorf("extractRegion(3, 6) = %X, want %X", got, want)
+	}
+	// Single right-edge pixel
+	if got, want := dev.extractRegion(7, 7, 0, 0), []byte{0x67}; !bytes.Equal(got, want) {
+		t.Errorf("extractRegion(7, 7) = %X, want %X", got, want)
+	}
+}
+
+func TestDrawRightEdgePixel(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	img.SetGray4(7, 1, image4bit.Gray4{Y: 0xC})
+	// Wrap the image to bypass the full-frame fast path and exercise the diff
+	if err := dev.Draw(dev.Bounds(), struct{ image.Image }{img}, image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+
+	wantCmd := []byte{0x15, dev.ramColumn(6), dev.ramColumn(7), 0x75, 1, 1, 0x5C}
+	if len(bus.txs) != 2 || !bytes.Equal(bus.txs[0].w, wantCmd) {
+		t.Fatalf("Draw sent %v, want commands %X", bus.txs, wantCmd)
+	}
+	if !bytes.Equal(bus.txs[1].w, []byte{0x0C}) {
+		t.Errorf("Draw data = %X, want 0C", bus.txs[1].w)
+	}
+}
+
 func TestScrollSpeed(t *testing.T) {
 	tests := []struct {
 		name string
@@ -259,6 +533,47 @@ func TestScrollSpeed(t *testing.T) {
 	}
 }
 
+func TestScrollSpeedForFPS(t *testing.T) {
+	tests := []struct {
+		fps     int
+		want    ScrollSpeed
+		wantErr bool
+	}{
+		{0, 0, true},
+		{-5, 0, true},
+		{1, Speed100Frames, false},
+		{4, Speed10Frames, false},
+		{10, Speed10Frames, false},
+		{14, Speed6Frames, false},
+		{60, Speed6Frames, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ScrollSpeedForFPS(tt.fps)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ScrollSpeedForFPS(%d) error = %v, want error = %v", tt.fps, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ScrollSpeedForFPS(%d) = %d, want %d", tt.fps, got, tt.want)
+		}
+	}
+}
+
+func TestScrollHorizontalInvalidSpeed(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	if err := dev.ScrollHorizontal(0, 1, ScrollSpeed(4), false); err == nil {
+		t.Error("ScrollHorizontal should fail with an invalid speed")
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("invalid ScrollHorizontal sent %d transfers, want 0", len(bus.txs))
+	}
+	if err := dev.ScrollHorizontal(0, 1, Speed200Frames, true); err != nil {
+		t.Errorf("ScrollHorizontal(Speed200Frames) error = %v", err)
+	}
+}
+
 func TestWriteBufferSizeValidation(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -275,8 +590,9 @@ func TestWriteBufferSizeValidation(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			dev := &Dev{
-				rect:   image.Rect(0, 0, tt.width, tt.height),
-				buffer: make([]byte, tt.width*tt.height/2),
+				rect:        image.Rect(0, 0, tt.width, tt.height),
+				buffer:      make([]byte, tt.width*tt.height/2),
+				initialized: true,
 			}
 
 			_, err := dev.Write(make([]byte, tt.bufferSize))
@@ -333,3 +649,3475 @@ func TestRSTOptionInOpts(t *testing.T) {
 	// This is a compile-time check that the field exists
 	_ = &Opts{W: 256, H: 64, RST: opts.RST}
 }
+
+func TestNewSPIPreallocatesBuffers(t *testing.T) {
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 2})
+
+	if dev.next == nil || len(dev.next.Pix) != len(dev.buffer) {
+		t.Fatal("next buffer should be allocated by NewSPI")
+	}
+	if len(dev.lastDm.Pix) != len(dev.buffer) {
+		t.Fatal("lastDm buffer should be allocated by NewSPI")
+	}
+}
+
+func TestDrawAfterWrite(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+	next, last := dev.next, &dev.lastDm.Pix[0]
+
+	frame := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	bus.reset()
+
+	// Redrawing part of the written frame unchanged must not transmit anything
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	copy(img.Pix, frame)
+	if err := dev.Draw(image.Rect(0, 0, 4, 1), img, image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("Draw of unchanged region sent %d transfers, want 0", len(bus.txs))
+	}
+
+	// Changing one pixel must only send the byte containing it
+	img.SetGray4(0, 0, image4bit.Gray4{Y: 0xF})
+	if err := dev.Draw(image.Rect(0, 0, 4, 1), img, image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	data := bus.data()
+	if len(data) != 1 || !bytes.Equal(data[0], []byte{0xF1}) {
+		t.Errorf("Draw sent data %X, want [F1]", data)
+	}
+	if !bytes.Equal(dev.buffer, []byte{0xF1, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}) {
+		t.Errorf("buffer = %X after Draw", dev.buffer)
+	}
+
+	// Buffers must not have been reallocated
+	if dev.next != next || &dev.lastDm.Pix[0] != last {
+		t.Error("Draw reallocated the double-buffer state")
+	}
+}
+
+func TestMarkDirtyMerge(t *testing.T) {
+	dev := &Dev{rect: image.Rect(0, 0, 16, 8)}
+
+	dev.MarkDirty(image.Rect(1, 0, 3, 2))   // widened to 0-4
+	dev.MarkDirty(image.Rect(10, 4, 12, 6)) // disjoint
+	dev.MarkDirty(image.Rect(2, 1, 6, 3))   // overlaps the first
+	dev.MarkDirty(image.Rect(20, 0, 30, 4)) // outside the display
+
+	want := []image.Rectangle{
+		image.Rect(10, 4, 12, 6),
+		image.Rect(0, 0, 6, 3),
+	}
+	if len(dev.dirty) != len(want) {
+		t.Fatalf("dirty = %v, want %v", dev.dirty, want)
+	}
+	for i := range want {
+		if dev.dirty[i] != want[i] {
+			t.Errorf("dirty[%d] = %v, want %v", i, dev.dirty[i], want[i])
+		}
+	}
+}
+
+func TestFlushTransmitsMarkedRegions(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 4})
+
+	frame := []byte{
+		0x01, 0x02, 0x03, 0x04,
+		0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0A, 0x0B, 0x0C,
+		0x0D, 0x0E, 0x0F, 0x10,
+	}
+	if err := dev.SetBuffer(frame); err != nil {
+		t.Fatalf("SetBuffer() error = %v", err)
+	}
+	if len(bus.txs) != 0 {
+		t.Fatal("SetBuffer should not transmit")
+	}
+
+	dev.MarkDirty(image.Rect(0, 0, 2, 1))
+	dev.MarkDirty(image.Rect(4, 2, 8, 4))
+	if err := dev.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := [][]byte{
+		{0x01},
+		{0x0B, 0x0C, 0x0F, 0x10},
+	}
+	data := bus.data()
+	if len(data) != len(want) {
+		t.Fatalf("Flush sent %d data transfers, want %d", len(data), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(data[i], want[i]) {
+			t.Errorf("data[%d] = %X, want %X", i, data[i], want[i])
+		}
+	}
+
+	// The unmarked changes are still pending, and the next differential
+	// update sends them
+	bus.reset()
+	if err := dev.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if data := bus.data(); len(data) != 1 || !bytes.Equal(data[0], frame) {
+		t.Errorf("second Flush sent %X, want the whole frame %X", data, frame)
+	}
+
+	// Nothing left to flush
+	bus.reset()
+	if err := dev.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("third Flush sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+func TestFlushKeepsUnmarkedChanges(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	// Change a marked and an unmarked pixel, and flush the marked one only
+	img := dev.Image()
+	img.SetGray4(0, 0, image4bit.Gray4{Y: 0x3})
+	img.SetGray4(6, 1, image4bit.Gray4{Y: 0x9})
+	dev.MarkDirty(image.Rect(0, 0, 2, 1))
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := dev.buffer[7]; got != 0x00 {
+		t.Errorf("unsent pixel stored as displayed: buffer[7] = %02X, want 00", got)
+	}
+
+	// A later Draw sends the unmarked pixel along with its own change
+	bus.reset()
+	src := image4bit.NewHorizontalNibble(dev.Bounds())
+	src.SetGray4(0, 0, image4bit.Gray4{Y: 0x3})
+	src.SetGray4(6, 1, image4bit.Gray4{Y: 0x9})
+	if err := dev.Draw(image.Rect(0, 0, 2, 1), src, image.Point{}); err != nil {
+		t.Fatal(err)
+	}
+	data := bus.data()
+	if len(data) != 1 || !bytes.Contains(data[0], []byte{0x90}) {
+		t.Errorf("Draw sent %X, want the unmarked pixel 90 included", data)
+	}
+	if got := dev.buffer[7]; got != 0x90 {
+		t.Errorf("buffer[7] after Draw = %02X, want 90", got)
+	}
+}
+
+func TestContrastCurve(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve ContrastCurve
+		in    byte
+		want  byte
+	}{
+		{"linear low", ContrastLinear, 64, 64},
+		{"linear mid", ContrastLinear, 128, 128},
+		{"s-curve low is compressed", ContrastSCurve, 64, 40},
+		{"s-curve mid is unchanged", ContrastSCurve, 128, 128},
+		{"s-curve high is expanded", ContrastSCurve, 192, 216},
+		{"log low is expanded", ContrastLogarithmic, 64, 131},
+		{"log mid is expanded", ContrastLogarithmic, 128, 189},
+		{"log max", ContrastLogarithmic, 255, 255},
+		{"s-curve min", ContrastSCurve, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+			dev.SetContrastCurve(tt.curve)
+			if err := dev.SetContrast(tt.in); err != nil {
+				t.Fatalf("SetContrast() error = %v", err)
+			}
+			want := []byte{0xC1, tt.want}
+			if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, want) {
+				t.Errorf("SetContrast(%d) sent %v, want %X", tt.in, bus.txs, want)
+			}
+		})
+	}
+}
+
+func TestDrawIdenticalFrame(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	img.SetGray4(0, 0, image4bit.Gray4{Y: 9})
+	r := image.Rect(0, 0, 4, 2)
+	if err := dev.Draw(r, img, image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+
+	// Write changes the display behind Draw's back; the next Draw of the
+	// old image must not be a no-op.
+	if _, err := dev.Write(make([]byte, 8)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	bus.reset()
+	if err := dev.Draw(r, img, image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	if len(bus.data()) != 1 {
+		t.Errorf("Draw after Write sent %d data transfers, want 1", len(bus.data()))
+	}
+
+	// Drawing the same image again is skipped
+	bus.reset()
+	if err := dev.Draw(r, img, image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("identical Draw sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+// BenchmarkDrawIdentical measures repeated Draws of an unchanged image, which
+// are resolved by one comparison with the displayed frame without running
+// calculateDiff.
+func BenchmarkDrawIdentical(b *testing.B) {
+	bus := &fakeBus{}
+	dev, err := NewSPI(bus, &fakeDC{bus: bus}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	img.SetGray4(100, 30, image4bit.Gray4{Y: 15})
+	r := image.Rect(0, 0, 200, 64)
+	if err := dev.Draw(r, img, image.Point{}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := dev.Draw(r, img, image.Point{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCalculateDiffIdentical measures the full diff scan that
+// BenchmarkDrawIdentical avoids.
+func BenchmarkCalculateDiffIdentical(b *testing.B) {
+	bus := &fakeBus{}
+	dev, err := NewSPI(bus, &fakeDC{bus: bus}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dev.calculateDiff()
+	}
+}
+
+// BenchmarkCalculateDiff measures the comparison alone for an unchanged
+// frame, a single changed pixel and a fully changed frame.
+func BenchmarkCalculateDiff(b *testing.B) {
+	for _, tc := range []struct {
+		name   string
+		change func(img *image4bit.HorizontalNibble)
+	}{
+		{"NoChange", func(*image4bit.HorizontalNibble) {}},
+		{"SmallChange", func(img *image4bit.HorizontalNibble) {
+			img.SetGray4(200, 40, image4bit.Gray4{Y: 15})
+		}},
+		{"FullChange", func(img *image4bit.HorizontalNibble) {
+			img.Fill(image4bit.Gray4{Y: 15})
+		}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			bus := &fakeBus{}
+			dev, err := NewSPI(bus, &fakeDC{bus: bus}, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			tc.change(dev.next)
+			b.SetBytes(int64(len(dev.next.Pix)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				dev.calculateDiff()
+			}
+		})
+	}
+}
+
+func TestRemapMirroring(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Opts
+		wantRemap1 byte
+	}{
+		{"default", Opts{}, 0x14},
+		{"mirror x", Opts{MirrorX: true}, 0x16},
+		{"mirror y", Opts{MirrorY: true}, 0x04},
+		{"mirror both", Opts{MirrorX: true, MirrorY: true}, 0x06},
+		{"rotated", Opts{Rotated: true}, 0x06},
+		{"rotated and mirror x", Opts{Rotated: true, MirrorX: true}, 0x04},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeBus{}
+			opts := tt.opts
+			opts.W, opts.H = 256, 64
+			if _, err := NewSPI(bus, &fakeDC{bus: bus}, &opts); err != nil {
+				t.Fatalf("NewSPI() error = %v", err)
+			}
+
+			init := bus.txs[0].w
+			i := bytes.IndexByte(init, 0xA0)
+			if i < 0 || i+2 >= len(init) {
+				t.Fatalf("remap command not found in %X", init)
+			}
+			if init[i+1] != tt.wantRemap1 || init[i+2] != 0x11 {
+				t.Errorf("remap = %02X %02X, want %02X 11", init[i+1], init[i+2], tt.wantRemap1)
+			}
+		})
+	}
+}
+
+func TestComputeRemap(t *testing.T) {
+	// The first byte depends only on the orientation flags and the second
+	// only on the COM flags, so every combination is covered by checking
+	// each half against its own table
+	remap1 := map[[3]bool]byte{ // Rotated, MirrorX, MirrorY
+		{false, false, false}: 0x14,
+		{false, true, false}:  0x16,
+		{false, false, true}:  0x04,
+		{false, true, true}:   0x06,
+		{true, false, false}:  0x06,
+		{true, true, false}:   0x04,
+		{true, false, true}:   0x16,
+		{true, true, true}:    0x14,
+	}
+	remap2 := map[[2]bool]byte{ // Sequential, SwapTopBottom
+		{false, false}: 0x11,
+		{true, false}:  0x11,
+		{false, true}:  0x13,
+		{true, true}:   0x13,
+	}
+
+	for i := 0; i < 32; i++ {
+		bit := func(n int) bool { return i&(1<<n) != 0 }
+		opts := Opts{
+			Rotated:       bit(0),
+			MirrorX:       bit(1),
+			MirrorY:       bit(2),
+			Sequential:    bit(3),
+			SwapTopBottom: bit(4),
+		}
+		got1, got2 := ComputeRemap(opts)
+		want1 := remap1[[3]bool{opts.Rotated, opts.MirrorX, opts.MirrorY}]
+		want2 := remap2[[2]bool{opts.Sequential, opts.SwapTopBottom}]
+		if got1 != want1 || got2 != want2 {
+			t.Errorf("ComputeRemap(%+v) = %02X %02X, want %02X %02X", opts, got1, got2, want1, want2)
+		}
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 256, H: 64})
+
+	if err := dev.Reconfigure(128, 32); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	if got, want := dev.Bounds(), image.Rect(0, 0, 128, 32); got != want {
+		t.Errorf("Bounds() = %v, want %v", got, want)
+	}
+	if len(dev.buffer) != 128*32/2 || len(dev.next.Pix) != 128*32/2 || len(dev.lastDm.Pix) != 128*32/2 {
+		t.Errorf("buffer sizes = %d/%d/%d, want %d", len(dev.buffer), len(dev.next.Pix), len(dev.lastDm.Pix), 128*32/2)
+	}
+	if dev.columnOffset != 176 {
+		t.Errorf("columnOffset = %d, want 176", dev.columnOffset)
+	}
+
+	// Init is re-run with the new MUX ratio
+	if len(bus.txs) == 0 || !bytes.Contains(bus.txs[0].w, []byte{0xCA, 31}) {
+		t.Errorf("Reconfigure did not send MUX ratio 31")
+	}
+
+	// Invalid dimensions are rejected and leave the device unchanged
+	for _, size := range [][2]int{{481, 32}, {0, 32}, {512, 32}, {128, 0}, {128, 200}} {
+		if err := dev.Reconfigure(size[0], size[1]); err == nil {
+			t.Errorf("Reconfigure(%d, %d) should fail", size[0], size[1])
+		}
+	}
+	if got, want := dev.Bounds(), image.Rect(0, 0, 128, 32); got != want {
+		t.Errorf("Bounds() after invalid Reconfigure = %v, want %v", got, want)
+	}
+}
+
+func TestGrayscaleTableSelection(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	if dev.CustomGrayscaleActive() {
+		t.Error("default grayscale table should be active after init")
+	}
+
+	// Nothing to re-enable yet
+	if err := dev.EnableGrayscaleTable(); err == nil {
+		t.Error("EnableGrayscaleTable should fail without a custom table")
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("failed EnableGrayscaleTable sent %d transfers, want 0", len(bus.txs))
+	}
+
+	table := [15]byte{1, 2, 3, 4, 5, 6, 7, 8, 20, 40, 60, 80, 100, 140, 180}
+	if err := dev.SetGrayscaleTable(table); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.UseDefaultGrayscale(); err != nil {
+		t.Fatalf("UseDefaultGrayscale() error = %v", err)
+	}
+	if dev.CustomGrayscaleActive() {
+		t.Error("default table should be active after UseDefaultGrayscale")
+	}
+
+	// The default table overwrote the gray scale registers, so the custom
+	// values are loaded again before being enabled
+	bus.reset()
+	if err := dev.EnableGrayscaleTable(); err != nil {
+		t.Fatalf("EnableGrayscaleTable() error = %v", err)
+	}
+	if !dev.CustomGrayscaleActive() {
+		t.Error("custom table should be active after EnableGrayscaleTable")
+	}
+	if err := dev.UseDefaultGrayscale(); err != nil {
+		t.Fatalf("UseDefaultGrayscale() error = %v", err)
+	}
+
+	enable := append(append([]byte{0xB8}, table[:]...), 0x00)
+	want := [][]byte{enable, {0xB9}}
+	if len(bus.txs) != len(want) {
+		t.Fatalf("sent %d transfers, want %d", len(bus.txs), len(want))
+	}
+	for i := range want {
+		if bus.txs[i].dc != gpio.Low || !bytes.Equal(bus.txs[i].w, want[i]) {
+			t.Errorf("tx[%d] = %X (dc=%v), want command %X", i, bus.txs[i].w, bus.txs[i].dc, want[i])
+		}
+	}
+}
+
+func TestSetStartColumn(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+	copy(dev.buffer, []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF})
+
+	// columnOffset = (480 - 8) / 2 = 236; pan by 4 -> RAM columns 240..247
+	if err := dev.SetStartColumn(4); err != nil {
+		t.Fatalf("SetStartColumn() error = %v", err)
+	}
+	if len(bus.txs) != 2 {
+		t.Fatalf("sent %d transfers, want 2", len(bus.txs))
+	}
+	want := []byte{0x15, 120, 123, 0x75, 0, 1, 0x5C}
+	if !bytes.Equal(bus.txs[0].w, want) {
+		t.Errorf("commands = %X, want %X", bus.txs[0].w, want)
+	}
+	if !bytes.Equal(bus.txs[1].w, dev.buffer) {
+		t.Errorf("data = %X, want frame buffer %X", bus.txs[1].w, dev.buffer)
+	}
+
+	// Later writes keep the pan
+	bus.reset()
+	if _, err := dev.Write(make([]byte, 8)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !bytes.Equal(bus.txs[0].w, want) {
+		t.Errorf("Write commands = %X, want %X", bus.txs[0].w, want)
+	}
+
+	// Negative pans move left
+	bus.reset()
+	if err := dev.SetStartColumn(-236); err != nil {
+		t.Fatalf("SetStartColumn(-236) error = %v", err)
+	}
+	if want := []byte{0x15, 0, 3}; !bytes.HasPrefix(bus.txs[0].w, want) {
+		t.Errorf("commands = %X, want prefix %X", bus.txs[0].w, want)
+	}
+
+	for _, offset := range []int{3, -238, 238} {
+		if err := dev.SetStartColumn(offset); err == nil {
+			t.Errorf("SetStartColumn(%d) should fail", offset)
+		}
+	}
+}
+
+func TestDrawDegenerate(t *testing.T) {
+	src := image4bit.NewHorizontalNibble(image.Rect(0, 0, 4, 2))
+	for i := range src.Pix {
+		src.Pix[i] = 0xFF
+	}
+
+	tests := []struct {
+		name string
+		dst  image.Rectangle
+		src  image.Image
+		sp   image.Point
+	}{
+		{"dst empty", image.Rectangle{}, src, image.Point{}},
+		{"dst outside display", image.Rect(8, 0, 16, 2), src, image.Point{}},
+		{"dst negative outside display", image.Rect(-8, -2, -1, 0), src, image.Point{}},
+		{"sp beyond src bounds", image.Rect(0, 0, 8, 2), src, image.Pt(4, 0)},
+		{"sp far beyond src bounds", image.Rect(0, 0, 8, 2), src, image.Pt(1000, 1000)},
+		{"negative sp before src bounds", image.Rect(0, 0, 4, 2), src, image.Pt(-4, -2)},
+		{"empty src", image.Rect(0, 0, 8, 2), image4bit.NewHorizontalNibble(image.Rectangle{}), image.Point{}},
+		{"fully off-screen src", image.Rect(0, 0, 8, 2), image4bit.NewHorizontalNibble(image.Rect(100, 100, 104, 102)), image.Point{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+			if err := dev.Draw(tt.dst, tt.src, tt.sp); err != nil {
+				t.Fatalf("Draw() error = %v", err)
+			}
+			if len(bus.txs) != 0 {
+				t.Errorf("Draw sent %d transfers, want 0", len(bus.txs))
+			}
+			if !bytes.Equal(dev.next.Pix, make([]byte, 8)) {
+				t.Errorf("Draw modified next buffer: %X", dev.next.Pix)
+			}
+		})
+	}
+}
+
+func TestDrawClippedAlignment(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	src := image4bit.NewHorizontalNibble(image.Rect(0, 0, 8, 2))
+	for x := 0; x < 8; x++ {
+		src.SetGray4(x, 0, image4bit.Gray4{Y: uint8(x + 1)})
+	}
+
+	// dst starts 2 pixels left of the display, so display column 0 shows
+	// source column 2
+	if err := dev.Draw(image.Rect(-2, 0, 2, 1), src, image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	data := bus.data()
+	if len(data) != 1 || !bytes.Equal(data[0], []byte{0x34}) {
+		t.Errorf("Draw sent %X, want [34]", data)
+	}
+}
+
+func TestImageFlush(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 4})
+
+	img := dev.Image()
+	if img.Bounds() != dev.Bounds() {
+		t.Fatalf("Image().Bounds() = %v, want %v", img.Bounds(), dev.Bounds())
+	}
+
+	img.SetGray4(4, 2, image4bit.Gray4{Y: 0xA})
+	img.SetGray4(5, 3, image4bit.Gray4{Y: 0x5})
+	if len(bus.txs) != 0 {
+		t.Fatal("drawing into Image() should not transmit")
+	}
+
+	if err := dev.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	want := []byte{0x15, dev.ramColumn(4), dev.ramColumn(5), 0x75, 2, 3, 0x5C}
+	if len(bus.txs) != 2 || !bytes.Equal(bus.txs[0].w, want) {
+		t.Fatalf("Flush sent %v, want commands %X", bus.txs, want)
+	}
+	if !bytes.Equal(bus.txs[1].w, []byte{0xA0, 0x05}) {
+		t.Errorf("Flush data = %X, want A005", bus.txs[1].w)
+	}
+
+	// Nothing changed since the last Flush
+	bus.reset()
+	if err := dev.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("second Flush sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+func TestOddWidthPadding(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 5, H: 2})
+
+	if got, want := dev.Bounds(), image.Rect(0, 0, 5, 2); got != want {
+		t.Errorf("Bounds() = %v, want %v", got, want)
+	}
+	if len(dev.buffer) != 6 {
+		t.Fatalf("len(buffer) = %d, want 6 (padded to 6x2)", len(dev.buffer))
+	}
+	// Padded width 6 is centered: (480 - 6) / 2 = 237
+	if dev.columnOffset != 237 {
+		t.Errorf("columnOffset = %d, want 237", dev.columnOffset)
+	}
+
+	// Write blanks the padding pixel and stays within the padded window
+	if _, err := dev.Write([]byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	wantCmd := []byte{0x15, dev.ramColumn(0), dev.ramColumn(5), 0x75, 0, 1, 0x5C}
+	if !bytes.Equal(bus.txs[0].w, wantCmd) {
+		t.Errorf("Write commands = %X, want %X", bus.txs[0].w, wantCmd)
+	}
+	if want := []byte{0x12, 0x34, 0x50, 0x78, 0x9A, 0xB0}; !bytes.Equal(bus.txs[1].w, want) {
+		t.Errorf("Write data = %X, want %X", bus.txs[1].w, want)
+	}
+
+	// Draw clips to the logical bounds and never touches the padding column
+	bus.reset()
+	if err := dev.Draw(image.Rect(0, 0, 8, 2), image.NewUniform(image4bit.Gray4{Y: 15}), image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	if want := []byte{0xFF, 0xFF, 0xF0, 0xFF, 0xFF, 0xF0}; !bytes.Equal(dev.buffer, want) {
+		t.Errorf("buffer = %X, want %X", dev.buffer, want)
+	}
+
+	if got, want := dev.Image().Bounds(), image.Rect(0, 0, 6, 2); got != want {
+		t.Errorf("Image().Bounds() = %v, want %v", got, want)
+	}
+
+	// A full-size HorizontalNibble source is sent whole, still with a black
+	// padding pixel
+	full := image4bit.NewHorizontalNibble(image.Rect(0, 0, 6, 2))
+	full.Fill(image4bit.Gray4{Y: 7})
+	bus.reset()
+	if err := dev.Draw(dev.Bounds(), full.SubImage(dev.Bounds()), image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	want := []byte{0x77, 0x77, 0x70, 0x77, 0x77, 0x70}
+	if data := bus.data(); len(data) != 1 || !bytes.Equal(data[0], want) {
+		t.Errorf("full-frame Draw sent %X, want %X", data, want)
+	}
+	if !bytes.Equal(dev.buffer, want) {
+		t.Errorf("buffer = %X, want %X", dev.buffer, want)
+	}
+
+	// A source with another row stride is drawn pixel by pixel instead
+	wide := image4bit.NewHorizontalNibble(image.Rect(0, 0, 10, 2))
+	wide.FillRect(image.Rect(0, 0, 10, 1), image4bit.Gray4{Y: 1})
+	wide.FillRect(image.Rect(0, 1, 10, 2), image4bit.Gray4{Y: 2})
+	if err := dev.Draw(dev.Bounds(), wide.SubImage(dev.Bounds()), image.Point{}); err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	if want := []byte{0x11, 0x11, 0x10, 0x22, 0x22, 0x20}; !bytes.Equal(dev.buffer, want) {
+		t.Errorf("buffer = %X, want %X", dev.buffer, want)
+	}
+}
+
+func TestInitSettleDelays(t *testing.T) {
+	bus := &fakeBus{}
+	type sleepCall struct {
+		d       time.Duration
+		lastCmd []byte // Last transfer sent before sleeping
+	}
+	var sleeps []sleepCall
+	dev := &Dev{
+		bus: bus.transport(),
+		clock: Clock{Sleep: func(d time.Duration) {
+			sleeps = append(sleeps, sleepCall{d, bus.txs[len(bus.txs)-1].w})
+		}},
+	}
+	dev.setSize(8, 2)
+
+	opts := &Opts{
+		W: 8, H: 2,
+		UnlockDelay:    1 * time.Millisecond,
+		RemapDelay:     2 * time.Millisecond,
+		DisplayOnDelay: 3 * time.Millisecond,
+	}
+	if err := dev.init(opts); err != nil {
+		t.Fatalf("init() error = %v", err)
+	}
+
+	if len(sleeps) != 3 {
+		t.Fatalf("init slept %d times, want 3", len(sleeps))
+	}
+	if sleeps[0].d != time.Millisecond || !bytes.Equal(sleeps[0].lastCmd, []byte{0xFD, 0x12}) {
+		t.Errorf("first sleep = %v after %X, want 1ms after unlock", sleeps[0].d, sleeps[0].lastCmd)
+	}
+	if l := sleeps[1].lastCmd; sleeps[1].d != 2*time.Millisecond || len(l) < 3 || l[len(l)-3] != 0xA0 {
+		t.Errorf("second sleep = %v after %X, want 2ms after remap", sleeps[1].d, l)
+	}
+	if sleeps[2].d != 3*time.Millisecond || !bytes.Equal(sleeps[2].lastCmd, []byte{0xAF}) {
+		t.Errorf("third sleep = %v after %X, want 3ms after display ON", sleeps[2].d, sleeps[2].lastCmd)
+	}
+
+	// Without delays init sends the configuration as a single command blob
+	// and never sleeps
+	bus.reset()
+	sleeps = nil
+	if err := dev.init(&Opts{W: 8, H: 2}); err != nil {
+		t.Fatalf("init() error = %v", err)
+	}
+	if len(sleeps) != 0 {
+		t.Errorf("init without delays slept %d times", len(sleeps))
+	}
+	if !bytes.HasPrefix(bus.txs[0].w, []byte{0xFD, 0x12, 0xAE}) {
+		t.Errorf("first init transfer = %X, want unlock followed by display OFF", bus.txs[0].w)
+	}
+}
+
+func TestAlertStyle(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+	if err := dev.SetContrast(0x40); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+
+	if err := dev.AlertStyle(true); err != nil {
+		t.Fatalf("AlertStyle(true) error = %v", err)
+	}
+	// Turning it on twice must not overwrite the saved style
+	if err := dev.AlertStyle(true); err != nil {
+		t.Fatalf("AlertStyle(true) error = %v", err)
+	}
+	if err := dev.AlertStyle(false); err != nil {
+		t.Fatalf("AlertStyle(false) error = %v", err)
+	}
+
+	want := [][]byte{
+		{0xA7, 0xC1, 0xFF}, // Invert, then max contrast, in one transfer
+		{0xA6, 0xC1, 0x40}, // Restore normal display and previous contrast
+	}
+	if len(bus.txs) != len(want) {
+		t.Fatalf("sent %d transfers, want %d", len(bus.txs), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(bus.txs[i].w, want[i]) {
+			t.Errorf("tx[%d] = %X, want %X", i, bus.txs[i].w, want[i])
+		}
+	}
+	if dev.inverted || dev.contrast != 0x40 {
+		t.Errorf("state after restore = inverted %v contrast %#x, want false 0x40", dev.inverted, dev.contrast)
+	}
+
+	// An inverted display is restored as inverted
+	if err := dev.Invert(true); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+	if err := dev.AlertStyle(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.AlertStyle(false); err != nil {
+		t.Fatal(err)
+	}
+	if got := bus.txs[1].w; !bytes.Equal(got, []byte{0xA7, 0xC1, 0x40}) {
+		t.Errorf("restore = %X, want A7C140", got)
+	}
+}
+
+// fakeClock is a Clock whose time only advances when slept on.
+type fakeClock struct {
+	now    time.Time
+	sleeps int
+}
+
+func (c *fakeClock) clock() Clock {
+	return Clock{
+		Now:   func() time.Time { return c.now },
+		Sleep: func(d time.Duration) { c.now = c.now.Add(d); c.sleeps++ },
+	}
+}
+
+func TestFadeContrast(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2, Clock: clk.clock()})
+	if err := dev.SetContrast(0); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+
+	start := clk.now
+	if err := dev.FadeContrast(100, 100*time.Millisecond); err != nil {
+		t.Fatalf("FadeContrast() error = %v", err)
+	}
+
+	// 100ms at 20ms per step is 5 updates, each preceded by a sleep
+	want := []byte{20, 40, 60, 80, 100}
+	if len(bus.txs) != len(want) {
+		t.Fatalf("sent %d contrast updates, want %d", len(bus.txs), len(want))
+	}
+	for i, v := range want {
+		if !bytes.Equal(bus.txs[i].w, []byte{0xC1, v}) {
+			t.Errorf("step %d = %X, want C1%02X", i, bus.txs[i].w, v)
+		}
+	}
+	if clk.sleeps != 5 {
+		t.Errorf("slept %d times, want 5", clk.sleeps)
+	}
+	if got := clk.now.Sub(start); got != 100*time.Millisecond {
+		t.Errorf("fade took %v, want 100ms", got)
+	}
+
+	// A zero duration sets the contrast immediately
+	bus.reset()
+	clk.sleeps = 0
+	if err := dev.FadeContrast(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xC1, 0}) || clk.sleeps != 0 {
+		t.Errorf("short fade sent %d transfers after %d sleeps, want a single C100", len(bus.txs), clk.sleeps)
+	}
+
+	// Putting the device to sleep mid-fade stops the steps
+	sleep := dev.clock.Sleep
+	dev.clock.Sleep = func(d time.Duration) {
+		sleep(d)
+		if clk.sleeps == 2 {
+			if err := dev.Sleep(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	bus.reset()
+	clk.sleeps = 0
+	if err := dev.FadeContrast(0xFF, 100*time.Millisecond); !errors.Is(err, ErrSleeping) {
+		t.Errorf("FadeContrast() across Sleep error = %v, want ErrSleeping", err)
+	}
+	if last := bus.txs[len(bus.txs)-1].w; !bytes.Equal(last, []byte{0xAE}) {
+		t.Errorf("fade sent %X after Sleep, want nothing after AE", last)
+	}
+}
+
+func TestReadRAM(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 4})
+
+	if _, err := dev.ReadRAM(image.Rect(0, 0, 4, 2)); err == nil {
+		t.Error("ReadRAM() on a half-duplex connection succeeded, want error")
+	}
+
+	bus.full = true
+	bus.read = []byte{0xFF, 0x12, 0x34, 0x56, 0x78} // Dummy byte, then RAM
+	bus.reset()
+	got, err := dev.ReadRAM(image.Rect(2, 1, 6, 3))
+	if err != nil {
+		t.Fatalf("ReadRAM() error = %v", err)
+	}
+	if want := []byte{0x12, 0x34, 0x56, 0x78}; !bytes.Equal(got, want) {
+		t.Errorf("ReadRAM() = %X, want %X", got, want)
+	}
+
+	// The window follows the column offset of the centered 8-pixel panel
+	col := byte((2 + dev.columnOffset) / 2)
+	wantCmd := []byte{0x15, col, col + 1, 0x75, 1, 2, 0x5D}
+	if len(bus.txs) != 2 || !bytes.Equal(bus.txs[0].w, wantCmd) {
+		t.Fatalf("commands = %X, want %X", bus.txs[0].w, wantCmd)
+	}
+	if tx := bus.txs[1]; tx.dc != gpio.High || len(tx.w) != 5 {
+		t.Errorf("read transfer = %d bytes with DC %v, want 5 bytes with DC high", len(tx.w), tx.dc)
+	}
+
+	bad := []image.Rectangle{
+		image.Rect(1, 0, 3, 1),  // Odd start column
+		image.Rect(0, 0, 10, 1), // Out of bounds
+		image.Rect(0, 0, 0, 0),  // Empty
+	}
+	for _, r := range bad {
+		if _, err := dev.ReadRAM(r); err == nil {
+			t.Errorf("ReadRAM(%v) succeeded, want error", r)
+		}
+	}
+}
+
+func TestMinFrameInterval(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2, Clock: clk.clock()})
+	dev.SetMinFrameInterval(50 * time.Millisecond)
+
+	// Record the clock at every frame transmit
+	var sent []time.Time
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	for i := 0; i < 4; i++ {
+		img.SetGray4(i, 0, image4bit.Gray4{Y: 15})
+		n := len(bus.data())
+		if err := dev.Draw(dev.Bounds(), img, image.Point{}); err != nil {
+			t.Fatalf("Draw() error = %v", err)
+		}
+		if len(bus.data()) != n+1 {
+			t.Fatalf("Draw() %d sent no frame", i)
+		}
+		sent = append(sent, clk.now)
+		clk.now = clk.now.Add(10 * time.Millisecond) // Render time
+	}
+	for i := 1; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < 50*time.Millisecond {
+			t.Errorf("frames %d and %d sent %v apart, want at least 50ms", i-1, i, gap)
+		}
+	}
+
+	// Flushing an unchanged frame transmits nothing and so does not wait
+	clk.sleeps = 0
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if clk.sleeps != 0 {
+		t.Errorf("unchanged Flush slept %d times, want 0", clk.sleeps)
+	}
+
+	// Without an interval nothing sleeps
+	dev.SetMinFrameInterval(0)
+	if _, err := dev.Write(make([]byte, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if clk.sleeps != 0 {
+		t.Errorf("unpaced Write slept %d times, want 0", clk.sleeps)
+	}
+}
+
+func TestMinFrameIntervalUnlocked(t *testing.T) {
+	// A paced Draw waits without the lock, so other methods still run
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	slept, wake := make(chan struct{}), make(chan struct{})
+	clock := clk.clock()
+	sleep := clock.Sleep
+	clock.Sleep = func(d time.Duration) {
+		slept <- struct{}{}
+		<-wake
+		sleep(d)
+	}
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2, Clock: clock})
+	dev.SetMinFrameInterval(50 * time.Millisecond)
+	if _, err := dev.Write(make([]byte, 8)); err != nil {
+		t.Fatal(err)
+	}
+
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	img.SetGray4(0, 0, image4bit.Gray4{Y: 15})
+	done := make(chan error)
+	go func() { done <- dev.Draw(dev.Bounds(), img, image.Point{}) }()
+	<-slept
+
+	contrast := make(chan error)
+	go func() { contrast <- dev.SetContrast(0x10) }()
+	select {
+	case err := <-contrast:
+		if err != nil {
+			t.Errorf("SetContrast() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SetContrast blocked while Draw waited for the frame interval")
+	}
+
+	close(wake)
+	if err := <-done; err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	if got := dev.buffer[0]; got != 0xF0 {
+		t.Errorf("buffer[0] = %02X after the paced Draw, want F0", got)
+	}
+	if n := len(bus.data()); n != 2 {
+		t.Errorf("sent %d frames, want 2", n)
+	}
+}
+
+func TestNotInitialized(t *testing.T) {
+	bus := &fakeBus{err: errors.New("bus error")}
+	dev := &Dev{bus: bus.transport()}
+	dev.setSize(8, 2)
+	if err := dev.init(&Opts{W: 8, H: 2}); err == nil {
+		t.Fatal("init() on a failing bus succeeded, want error")
+	}
+
+	// Every operation must fail without touching the bus, even once the bus
+	// works again
+	bus.err = nil
+	ops := map[string]func() error{
+		"Draw":         func() error { return dev.Draw(dev.Bounds(), image.White, image.Point{}) },
+		"Write":        func() error { _, err := dev.Write(make([]byte, 8)); return err },
+		"Flush":        func() error { return dev.Flush() },
+		"SetContrast":  func() error { return dev.SetContrast(0x80) },
+		"Invert":       func() error { return dev.Invert(true) },
+		"StopScroll":   func() error { return dev.StopScroll() },
+		"FadeContrast": func() error { return dev.FadeContrast(0, 0) },
+	}
+	for name, op := range ops {
+		if err := op(); err == nil || err.Error() != "ssd1322: not initialized" {
+			t.Errorf("%s() error = %v, want not initialized", name, err)
+		}
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("uninitialized device sent %d transfers", len(bus.txs))
+	}
+
+	// A successful Reconfigure recovers the device
+	if err := dev.Reconfigure(8, 2); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	if err := dev.SetContrast(0x80); err != nil {
+		t.Errorf("SetContrast() after Reconfigure error = %v", err)
+	}
+}
+
+func TestInitSequence(t *testing.T) {
+	want := []byte{
+		0xFD, 0x12, // Unlock
+		0xAE,       // Display OFF
+		0xB3, 0xF2, // Clock divider and oscillator frequency
+		0xCA, 0x3F, // MUX ratio (64 rows)
+		0xA2, 0x00, // Display offset
+		0xA1, 0x00, // Start line
+		0xA0, 0x14, 0x11, // Remap and dual COM mode
+		0xAB, 0x01, // Internal VDD
+		0xB4, 0xA0, 0xFD, // VSL
+		0xC1, 0xFF, // Contrast
+		0xC7, 0x0F, // Master contrast
+		0xB9,       // Default grayscale table
+		0xB1, 0xE2, // Phase length
+		0xD1, 0x82, 0x20, // Display enhancements
+		0xBB, 0x1F, // Pre-charge voltage
+		0xB6, 0x08, // Second pre-charge period
+		0xBE, 0x07, // VCOMH voltage
+		0xA6, // Normal display
+		0xA9, // Exit partial display
+	}
+	got := InitSequence(Opts{W: 256, H: 64})
+	if !bytes.Equal(got, want) {
+		t.Errorf("InitSequence() = %X, want %X", got, want)
+	}
+
+	// NewSPI sends this sequence first, batched with the RAM clear window
+	bus := &fakeBus{}
+	if _, err := NewSPI(bus, &fakeDC{bus: bus}, nil); err != nil {
+		t.Fatalf("NewSPI() error = %v", err)
+	}
+	if !bytes.HasPrefix(bus.txs[0].w, want) {
+		t.Errorf("NewSPI() sent %X, want prefix %X", bus.txs[0].w, want)
+	}
+}
+
+func TestClearRegion(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 3})
+	frame := bytes.Repeat([]byte{0xFF}, 12)
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+
+	// Columns 1-4 of row 1: both edges are odd, so the transfer covers
+	// columns 0-5 and keeps pixels 0 and 5 lit
+	if err := dev.ClearRegion(image.Rect(1, 1, 5, 2)); err != nil {
+		t.Fatalf("ClearRegion() error = %v", err)
+	}
+	data := bus.data()
+	if len(data) != 1 || !bytes.Equal(data[0], []byte{0xF0, 0x00, 0x0F}) {
+		t.Errorf("transmitted %X, want [F0000F]", data)
+	}
+	col := byte(dev.columnOffset / 2)
+	wantCmd := []byte{0x15, col, col + 2, 0x75, 1, 1, 0x5C}
+	if !bytes.Equal(bus.txs[0].w, wantCmd) {
+		t.Errorf("window = %X, want %X", bus.txs[0].w, wantCmd)
+	}
+
+	want := []byte{
+		0xFF, 0xFF, 0xFF, 0xFF,
+		0xF0, 0x00, 0x0F, 0xFF,
+		0xFF, 0xFF, 0xFF, 0xFF,
+	}
+	if !bytes.Equal(dev.buffer, want) || !bytes.Equal(dev.lastDm.Pix, want) || !bytes.Equal(dev.next.Pix, want) {
+		t.Errorf("buffers = %X/%X/%X, want %X", dev.buffer, dev.lastDm.Pix, dev.next.Pix, want)
+	}
+
+	// The cleared region is now in sync, so the next diff sends nothing
+	bus.reset()
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("Flush() after ClearRegion sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+func TestVerifyLastWrite(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	if _, err := dev.VerifyLastWrite(); err == nil {
+		t.Error("VerifyLastWrite() before any write succeeded, want error")
+	}
+
+	// Change pixels 2-4 of row 1, which is sent as columns 2-5
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	for x := 2; x < 5; x++ {
+		img.SetGray4(x, 1, image4bit.Gray4{Y: 7})
+	}
+	if err := dev.Draw(image.Rect(2, 1, 5, 2), img, image.Pt(2, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dev.VerifyLastWrite(); err == nil {
+		t.Error("VerifyLastWrite() on a half-duplex connection succeeded, want error")
+	}
+
+	// Read-back starts with a dummy byte
+	bus.full = true
+	tests := []struct {
+		name string
+		ram  []byte
+		want bool
+	}{
+		{"intact", []byte{0x00, 0x77, 0x70}, true},
+		{"corrupted", []byte{0x00, 0x77, 0x71}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus.read = tt.ram
+			ok, err := dev.VerifyLastWrite()
+			if err != nil {
+				t.Fatalf("VerifyLastWrite() error = %v", err)
+			}
+			if ok != tt.want {
+				t.Errorf("VerifyLastWrite() = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+
+	// With ColumnInterleave the RAM holds the bytes in interleaved order
+	dev, bus = newTestDev(t, &Opts{W: 8, H: 2, ColumnInterleave: true})
+	for x := 2; x < 8; x++ {
+		img.SetGray4(x, 1, image4bit.Gray4{Y: uint8(x - 1)})
+	}
+	if err := dev.Draw(image.Rect(2, 1, 8, 2), img, image.Pt(2, 1)); err != nil {
+		t.Fatal(err)
+	}
+	bus.full = true
+	bus.read = []byte{0x00, 0x12, 0x56, 0x34}
+	if ok, err := dev.VerifyLastWrite(); err != nil || !ok {
+		t.Errorf("VerifyLastWrite() with ColumnInterleave = %v, %v, want true", ok, err)
+	}
+}
+
+func TestScrollContentVertical(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2, Clock: clk.clock()})
+
+	// Start near the end of RAM to cover the wrap-around, and cancel during
+	// the fifth wait
+	if err := dev.setStartLine(126); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var times []time.Duration
+	sleep := dev.clock.Sleep
+	dev.clock.Sleep = func(d time.Duration) {
+		sleep(d)
+		times = append(times, clk.now.Sub(time.Unix(0, 0)))
+		if len(times) == 5 {
+			cancel()
+		}
+	}
+
+	err := dev.ScrollContentVertical(ctx, 50)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScrollContentVertical() error = %v, want context.Canceled", err)
+	}
+
+	wantLines := []byte{127, 0, 1, 2}
+	if len(bus.txs) != len(wantLines) {
+		t.Fatalf("sent %d commands, want %d", len(bus.txs), len(wantLines))
+	}
+	for i, line := range wantLines {
+		if !bytes.Equal(bus.txs[i].w, []byte{0xA1, line}) {
+			t.Errorf("step %d = %X, want A1%02X", i, bus.txs[i].w, line)
+		}
+	}
+	for i, got := range times {
+		if want := time.Duration(i+1) * 20 * time.Millisecond; got != want {
+			t.Errorf("step %d at %v, want %v", i, got, want)
+		}
+	}
+
+	if err := dev.ScrollContentVertical(context.Background(), 0); err == nil {
+		t.Error("ScrollContentVertical() with zero rate succeeded, want error")
+	}
+}
+
+func TestTransmitHistory(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 4, Clock: clk.clock(), TransmitHistory: 2})
+
+	// Three single-pixel changes; only the last two are kept
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	for i := 0; i < 3; i++ {
+		clk.now = clk.now.Add(time.Second)
+		img.SetGray4(2*i+1, i, image4bit.Gray4{Y: 15})
+		if err := dev.Draw(image.Rect(0, 0, 8, 3), img, image.Point{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []TransmitRecord{
+		{Rect: image.Rect(2, 1, 4, 2), Bytes: 1, Time: time.Unix(2, 0)},
+		{Rect: image.Rect(4, 2, 6, 3), Bytes: 1, Time: time.Unix(3, 0)},
+	}
+	got := dev.TransmitHistory()
+	if len(got) != len(want) {
+		t.Fatalf("TransmitHistory() has %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Rect != want[i].Rect || got[i].Bytes != want[i].Bytes {
+			t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Disabled by default
+	dev, _ = newTestDev(t, &Opts{W: 8, H: 4})
+	if _, err := dev.Write(make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if h := dev.TransmitHistory(); h != nil {
+		t.Errorf("TransmitHistory() without Opts = %v, want nil", h)
+	}
+}
+
+func TestShowGradient(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 32, H: 16})
+	if err := dev.ShowGradient(true); err != nil {
+		t.Fatalf("ShowGradient() error = %v", err)
+	}
+
+	data := bus.data()
+	if len(data) != 1 || len(data[0]) != 32*16/2 {
+		t.Fatalf("ShowGradient() sent %d data transfers, want one full frame", len(data))
+	}
+	row := []byte{
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77,
+		0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF,
+	}
+	for y := 0; y < 16; y++ {
+		if got := data[0][y*16 : (y+1)*16]; !bytes.Equal(got, row) {
+			t.Fatalf("row %d = %X, want %X", y, got, row)
+		}
+	}
+	if !bytes.Equal(dev.lastDm.Pix, data[0]) {
+		t.Error("ShowGradient() did not update the last displayed frame")
+	}
+
+	// Vertically, row y is level y
+	bus.reset()
+	if err := dev.ShowGradient(false); err != nil {
+		t.Fatal(err)
+	}
+	if got := bus.data()[0][5*16]; got != 0x55 {
+		t.Errorf("vertical row 5 starts with %02X, want 55", got)
+	}
+}
+
+func TestMaxColumns(t *testing.T) {
+	// A virtual canvas wider than the controller's RAM
+	dev, _ := newTestDev(t, &Opts{W: 960, H: 64, MaxColumns: 1024})
+	if got, want := dev.Bounds(), image.Rect(0, 0, 960, 64); got != want {
+		t.Errorf("Bounds() = %v, want %v", got, want)
+	}
+	if dev.columnOffset != 32 {
+		t.Errorf("columnOffset = %d, want 32", dev.columnOffset)
+	}
+	if err := dev.Reconfigure(1000, 64); err != nil {
+		t.Errorf("Reconfigure(1000, 64) error = %v", err)
+	}
+	if err := dev.Reconfigure(1025, 64); err == nil {
+		t.Error("Reconfigure(1025, 64) succeeded, want error")
+	}
+
+	// Real hardware keeps the 480-column limit
+	bus := &fakeBus{}
+	if _, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 481, H: 64}); err == nil {
+		t.Error("NewSPI() with width 481 succeeded, want error")
+	}
+}
+
+func TestRawAddressing(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 256, H: 64, RawAddressing: true})
+	if dev.columnOffset != 0 {
+		t.Errorf("columnOffset = %d, want 0", dev.columnOffset)
+	}
+
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	img.SetGray4(0, 3, image4bit.Gray4{Y: 15})
+	if err := dev.Draw(image.Rect(0, 3, 2, 4), img, image.Pt(0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x15, 0, 0, 0x75, 3, 3, 0x5C}
+	if !bytes.Equal(bus.txs[0].w, want) {
+		t.Errorf("window = %X, want %X", bus.txs[0].w, want)
+	}
+}
+
+func TestDrawDitheredStaticRegions(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 32, H: 8})
+
+	// A smooth ramp between gray levels, which error diffusion turns into a
+	// mix of adjacent levels
+	src := image.NewGray(dev.Bounds())
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 32; x++ {
+			src.SetGray(x, y, color.Gray{Y: uint8(40 + 3*x + y)})
+		}
+	}
+	if err := dev.DrawDithered(src); err != nil {
+		t.Fatalf("DrawDithered() error = %v", err)
+	}
+	first := image4bit.NewHorizontalNibble(dev.Bounds())
+	copy(first.Pix, dev.lastDm.Pix)
+
+	// Change a small block; everything outside it must keep its bytes even
+	// though the diffused errors inside it differ
+	changed := image.Rect(20, 2, 24, 5)
+	for y := changed.Min.Y; y < changed.Max.Y; y++ {
+		for x := changed.Min.X; x < changed.Max.X; x++ {
+			src.SetGray(x, y, color.Gray{Y: 200})
+		}
+	}
+	bus.reset()
+	if err := dev.DrawDithered(src); err != nil {
+		t.Fatalf("DrawDithered() error = %v", err)
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 32; x++ {
+			got, want := dev.lastDm.Gray4At(x, y), first.Gray4At(x, y)
+			inside := image.Pt(x, y).In(changed)
+			if !inside && got != want {
+				t.Errorf("static pixel (%d, %d) = %d, want %d", x, y, got.Y, want.Y)
+			}
+			if inside && (got.Y < 11 || got.Y > 12) {
+				t.Errorf("changed pixel (%d, %d) = %d, want 11 or 12", x, y, got.Y)
+			}
+		}
+	}
+
+	// Only the changed block was sent
+	col := byte((20 + dev.columnOffset) / 2)
+	want := []byte{0x15, col, col + 1, 0x75, 2, 4, 0x5C}
+	if len(bus.txs) != 2 || !bytes.Equal(bus.txs[0].w, want) {
+		t.Errorf("window = %X, want %X", bus.txs[0].w, want)
+	}
+
+	// An identical source sends nothing
+	bus.reset()
+	if err := dev.DrawDithered(src); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("unchanged DrawDithered() sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+func TestDrawDitheredAfterOtherDraws(t *testing.T) {
+	white := image.NewUniform(color.White)
+	for _, tt := range []struct {
+		name  string
+		other func(dev *Dev) error
+	}{
+		{"Draw", func(dev *Dev) error {
+			return dev.Draw(dev.Bounds(), image4bit.NewHorizontalNibble(dev.Bounds()), image.Point{})
+		}},
+		{"Write", func(dev *Dev) error {
+			_, err := dev.Write(make([]byte, 8))
+			return err
+		}},
+		{"ClearRegion", func(dev *Dev) error { return dev.ClearRegion(dev.Bounds()) }},
+		{"SetBuffer", func(dev *Dev) error { return dev.SetBuffer(make([]byte, 8)) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, _ := newTestDev(t, &Opts{W: 8, H: 2})
+			if err := dev.DrawDithered(white); err != nil {
+				t.Fatalf("DrawDithered() error = %v", err)
+			}
+			if err := tt.other(dev); err != nil {
+				t.Fatal(err)
+			}
+
+			// The same source again must bring the white frame back
+			if err := dev.DrawDithered(white); err != nil {
+				t.Fatalf("DrawDithered() error = %v", err)
+			}
+			for i, b := range dev.buffer {
+				if b != 0xFF {
+					t.Fatalf("buffer[%d] = %#02x after %s, want 0xFF", i, b, tt.name)
+				}
+			}
+		})
+	}
+}
+
+func TestCalibrateContrast(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	dev, bus := newTestDev(t, &Opts{W: 32, H: 2, Clock: clk.clock()})
+
+	if _, err := dev.CalibrateContrast(context.Background()); err == nil {
+		t.Error("CalibrateContrast() without selector succeeded, want error")
+	}
+
+	// Pick the fourth step, checking it has been shown for long enough
+	var seen []byte
+	dev.SetCalibrationSelector(func(c byte) bool {
+		seen = append(seen, c)
+		if want := time.Unix(0, 0).Add(time.Duration(len(seen)) * 250 * time.Millisecond); !clk.now.Equal(want) {
+			t.Errorf("step %d selected at %v, want %v", len(seen), clk.now, want)
+		}
+		return c == 24
+	})
+	bus.reset()
+	got, err := dev.CalibrateContrast(context.Background())
+	if err != nil {
+		t.Fatalf("CalibrateContrast() error = %v", err)
+	}
+	if got != 24 || !bytes.Equal(seen, []byte{0, 8, 16, 24}) {
+		t.Errorf("CalibrateContrast() = %d after %v, want 24 after [0 8 16 24]", got, seen)
+	}
+
+	// The gradient is shown first, then one contrast command per step
+	if data := bus.data(); len(data) != 1 || data[0][15] != 0xFF {
+		t.Errorf("gradient not shown before the sweep")
+	}
+	var sweep []byte
+	for _, tx := range bus.txs {
+		if tx.dc == gpio.Low && len(tx.w) == 2 && tx.w[0] == 0xC1 {
+			sweep = append(sweep, tx.w[1])
+		}
+	}
+	if !bytes.Equal(sweep, seen) {
+		t.Errorf("contrast commands = %v, want %v", sweep, seen)
+	}
+
+	// Without a selection the previous contrast is restored
+	if err := dev.SetContrast(0x40); err != nil {
+		t.Fatal(err)
+	}
+	dev.SetCalibrationSelector(func(byte) bool { return false })
+	bus.reset()
+	if _, err := dev.CalibrateContrast(context.Background()); err == nil {
+		t.Error("CalibrateContrast() without selection succeeded, want error")
+	}
+	if last := bus.txs[len(bus.txs)-1].w; !bytes.Equal(last, []byte{0xC1, 0x40}) || dev.contrast != 0x40 {
+		t.Errorf("last command = %X, want C140", last)
+	}
+}
+
+func TestColumnInterleave(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 10, H: 2, ColumnInterleave: true})
+	frame := []byte{
+		0x01, 0x23, 0x45, 0x67, 0x89,
+		0xAB, 0xCD, 0xEF, 0x10, 0x32,
+	}
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x01, 0x45, 0x89, 0x23, 0x67,
+		0xAB, 0xEF, 0x32, 0xCD, 0x10,
+	}
+	if data := bus.data(); len(data) != 1 || !bytes.Equal(data[0], want) {
+		t.Errorf("sent %X, want [%X]", data, want)
+	}
+	if !bytes.Equal(dev.buffer, frame) {
+		t.Errorf("buffer = %X, want the unscrambled frame %X", dev.buffer, frame)
+	}
+
+	// A partial window is reordered within the window
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	copy(img.Pix, frame)
+	copy(img.Pix, []byte{0x11, 0x22, 0x33, 0x44})
+	bus.reset()
+	if err := dev.Draw(image.Rect(0, 0, 8, 1), img, image.Point{}); err != nil {
+		t.Fatal(err)
+	}
+	if data := bus.data(); len(data) != 1 || !bytes.Equal(data[0], []byte{0x11, 0x33, 0x22, 0x44}) {
+		t.Errorf("partial write sent %X, want [11332244]", data)
+	}
+}
+
+func TestDistinguishable(t *testing.T) {
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 2})
+	g := func(y uint8) image4bit.Gray4 { return image4bit.Gray4{Y: y} }
+
+	tests := []struct {
+		name     string
+		contrast byte
+		a, b     uint8
+		want     bool
+	}{
+		{"adjacent at low contrast", 0x10, 7, 8, false},
+		{"distant at low contrast", 0x10, 0, 15, true},
+		{"adjacent at full contrast", 0xFF, 7, 8, true},
+		{"same level", 0xFF, 5, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := dev.SetContrast(tt.contrast); err != nil {
+				t.Fatal(err)
+			}
+			if got := dev.Distinguishable(g(tt.a), g(tt.b)); got != tt.want {
+				t.Errorf("Distinguishable(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+
+	// A custom table that crushes the low levels together
+	if err := dev.SetContrast(0xFF); err != nil {
+		t.Fatal(err)
+	}
+	dev.grayTable = []byte{1, 2, 3, 4, 5, 6, 7, 8, 20, 40, 60, 80, 100, 140, 180}
+	dev.grayCustom = true
+	if dev.Distinguishable(g(1), g(2)) {
+		t.Error("Distinguishable(1, 2) with a crushed table = true, want false")
+	}
+	if !dev.Distinguishable(g(13), g(14)) {
+		t.Error("Distinguishable(13, 14) with a crushed table = false, want true")
+	}
+}
+
+func TestAutoFlush(t *testing.T) {
+	// Every sleep reports its duration and blocks until the test ticks
+	slept := make(chan time.Duration)
+	ticks := make(chan struct{})
+	clock := Clock{Sleep: func(d time.Duration) {
+		slept <- d
+		<-ticks
+	}}
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2, Clock: clock})
+
+	if err := dev.StartAutoFlush(50 * time.Millisecond); err != nil {
+		t.Fatalf("StartAutoFlush() error = %v", err)
+	}
+	if err := dev.StartAutoFlush(50 * time.Millisecond); err == nil {
+		t.Error("second StartAutoFlush() succeeded, want error")
+	}
+
+	for i := 0; i < 3; i++ {
+		if d := <-slept; d != 50*time.Millisecond {
+			t.Errorf("waited %v between flushes, want 50ms", d)
+		}
+		if n := len(bus.data()); n != i {
+			t.Fatalf("%d frames sent before tick %d, want %d", n, i, i)
+		}
+		dev.Update(func(img *image4bit.HorizontalNibble) {
+			img.SetGray4(i, 0, image4bit.Gray4{Y: 15})
+		})
+		ticks <- struct{}{}
+	}
+	<-slept // The third flush has completed
+	if n := len(bus.data()); n != 3 {
+		t.Errorf("sent %d frames, want 3", n)
+	}
+
+	// Ticks while sleeping are skipped without an error, and the changes
+	// made meanwhile are sent after Wake
+	if err := dev.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	dev.Update(func(img *image4bit.HorizontalNibble) {
+		img.SetGray4(3, 0, image4bit.Gray4{Y: 15})
+	})
+	ticks <- struct{}{}
+	<-slept
+	if n := len(bus.data()); n != 3 {
+		t.Errorf("sent %d frames while sleeping, want 3", n)
+	}
+	if err := dev.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	ticks <- struct{}{}
+	<-slept
+	if n := len(bus.data()); n != 4 {
+		t.Errorf("sent %d frames after Wake, want 4", n)
+	}
+
+	// Let the goroutine run freely until it notices the stop request
+	close(ticks)
+	go func() {
+		for range slept {
+		}
+	}()
+	if err := dev.StopAutoFlush(); err != nil {
+		t.Errorf("StopAutoFlush() error = %v", err)
+	}
+	close(slept)
+	if err := dev.StopAutoFlush(); err != nil {
+		t.Errorf("second StopAutoFlush() error = %v", err)
+	}
+}
+
+func TestSetPrechargeProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile PrechargeProfile
+		want    []byte // nil if invalid
+	}{
+		{"init defaults", PrechargeProfile{5, 14, 8}, []byte{0xB1, 0xE2, 0xB6, 0x08}},
+		{"longest", PrechargeProfile{31, 15, 15}, []byte{0xB1, 0xFF, 0xB6, 0x0F}},
+		{"shortest", PrechargeProfile{5, 3, 1}, []byte{0xB1, 0x32, 0xB6, 0x01}},
+		{"even phase 1", PrechargeProfile{6, 14, 8}, nil},
+		{"phase 1 too short", PrechargeProfile{3, 14, 8}, nil},
+		{"phase 1 too long", PrechargeProfile{33, 14, 8}, nil},
+		{"phase 2 too short", PrechargeProfile{5, 2, 1}, nil},
+		{"phase 2 too long", PrechargeProfile{5, 16, 8}, nil},
+		{"no second precharge", PrechargeProfile{5, 14, 0}, nil},
+		{"second precharge longer than phase 2", PrechargeProfile{5, 6, 8}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+			err := dev.SetPrechargeProfile(tt.profile)
+			if tt.want == nil {
+				if err == nil {
+					t.Error("SetPrechargeProfile() succeeded, want error")
+				}
+				if len(bus.txs) != 0 {
+					t.Errorf("invalid profile sent %d transfers", len(bus.txs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetPrechargeProfile() error = %v", err)
+			}
+			if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, tt.want) {
+				t.Errorf("sent %X, want %X", bus.txs[0].w, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitImage(t *testing.T) {
+	white := func(w, h int) image.Image {
+		img := image.NewGray(image.Rect(0, 0, w, h))
+		for i := range img.Pix {
+			img.Pix[i] = 0xFF
+		}
+		return img
+	}
+	tests := []struct {
+		name   string
+		src    image.Image
+		lit    image.Rectangle // Expected white area on the 32x8 panel
+		dither bool
+	}{
+		{"portrait", white(10, 40), image.Rect(15, 0, 17, 8), false},
+		{"landscape", white(64, 4), image.Rect(0, 3, 32, 5), false},
+		{"same aspect", white(64, 16), image.Rect(0, 0, 32, 8), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, _ := newTestDev(t, &Opts{W: 32, H: 8})
+			if err := FitImage(dev, tt.src, tt.dither); err != nil {
+				t.Fatalf("FitImage() error = %v", err)
+			}
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 32; x++ {
+					want := uint8(0)
+					if image.Pt(x, y).In(tt.lit) {
+						want = 15
+					}
+					if got := dev.lastDm.Gray4At(x, y).Y; got != want {
+						t.Errorf("pixel (%d, %d) = %d, want %d", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSizeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		w, h int
+		want error
+	}{
+		{"odd width", 255, 64, nil},
+		{"zero width", 0, 64, ErrWidthNotPositive},
+		{"negative width", -2, 64, ErrWidthNotPositive},
+		{"oversized width", 482, 64, ErrWidthTooLarge},
+		{"zero height", 256, 0, ErrHeightNotPositive},
+		{"negative height", 256, -1, ErrHeightNotPositive},
+		{"oversized height", 256, 129, ErrHeightTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeBus{}
+			_, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: tt.w, H: tt.h})
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Errorf("NewSPI(%dx%d) error = %v, want %v", tt.w, tt.h, err, tt.want)
+			}
+		})
+	}
+
+	dev, _ := newTestDev(t, nil)
+	if err := dev.Reconfigure(500, 64); !errors.Is(err, ErrWidthTooLarge) {
+		t.Errorf("Reconfigure(500, 64) error = %v, want %v", err, ErrWidthTooLarge)
+	}
+}
+
+func TestDiffDetails(t *testing.T) {
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 2, RecordDiffDetails: true})
+	img := dev.Image()
+	img.SetGray4(1, 0, image4bit.Gray4{Y: 0x5})
+	img.SetGray4(6, 1, image4bit.Gray4{Y: 0xA})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ByteChange{
+		{Offset: 0, Old: 0x00, New: 0x05},
+		{Offset: 7, Old: 0x00, New: 0xA0},
+	}
+	got := dev.DiffDetails()
+	if len(got) != len(want) {
+		t.Fatalf("DiffDetails() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("DiffDetails()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Disabled by default
+	dev, _ = newTestDev(t, &Opts{W: 8, H: 2})
+	dev.Image().SetGray4(1, 0, image4bit.Gray4{Y: 0x5})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := dev.DiffDetails(); got != nil {
+		t.Errorf("DiffDetails() without option = %v, want nil", got)
+	}
+}
+
+func TestSoftwareFlip(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2, SoftwareFlipH: true, SoftwareFlipV: true})
+	frame := []byte{
+		0x01, 0x23, 0x45, 0x67,
+		0x89, 0xAB, 0xCD, 0xEF,
+	}
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0xFE, 0xDC, 0xBA, 0x98,
+		0x76, 0x54, 0x32, 0x10,
+	}
+	if data := bus.data(); len(data) != 1 || !bytes.Equal(data[0], want) {
+		t.Errorf("sent %X, want [%X]", data, want)
+	}
+	if !bytes.Equal(dev.buffer, frame) {
+		t.Errorf("buffer = %X, want the unflipped frame %X", dev.buffer, frame)
+	}
+
+	// A partial update is written to the mirrored window
+	bus.reset()
+	dev.Image().SetGray4(0, 0, image4bit.Gray4{Y: 0xF})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	wantCmd := []byte{0x15, dev.ramColumn(6), dev.ramColumn(7), 0x75, 1, 1, 0x5C}
+	if len(bus.txs) != 2 || !bytes.Equal(bus.txs[0].w, wantCmd) {
+		t.Fatalf("txs = %d, want window command %X", len(bus.txs), wantCmd)
+	}
+	if !bytes.Equal(bus.txs[1].w, []byte{0x1F}) {
+		t.Errorf("partial write sent %X, want 1F", bus.txs[1].w)
+	}
+
+	// Vertical only keeps the row contents
+	dev, bus = newTestDev(t, &Opts{W: 8, H: 2, SoftwareFlipV: true})
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	want = []byte{
+		0x89, 0xAB, 0xCD, 0xEF,
+		0x01, 0x23, 0x45, 0x67,
+	}
+	if data := bus.data(); len(data) != 1 || !bytes.Equal(data[0], want) {
+		t.Errorf("vertical flip sent %X, want [%X]", data, want)
+	}
+}
+
+func TestInitDCTransitions(t *testing.T) {
+	bus := &fakeBus{}
+	dev, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 8, H: 2})
+	if err != nil {
+		t.Fatalf("NewSPI() error = %v", err)
+	}
+
+	// Sending the configuration, the RAM clear window and the zeros, and
+	// Display ON as separate transfers took 4 DC writes; batching the
+	// commands leaves one per change of kind
+	if bus.dcOuts != 3 {
+		t.Errorf("init DC writes = %d, want 3", bus.dcOuts)
+	}
+	if len(bus.txs) != 3 {
+		t.Fatalf("init transfers = %d, want 3", len(bus.txs))
+	}
+	seq := InitSequence(Opts{W: 8, H: 2})
+	window := []byte{0x15, dev.ramColumn(0), dev.ramColumn(7), 0x75, 0, 1, 0x5C}
+	want := []fakeTx{
+		{dc: gpio.Low, w: append(seq, window...)},
+		{dc: gpio.High, w: make([]byte, 8)},
+		{dc: gpio.Low, w: []byte{0xAF}},
+	}
+	for i, tx := range want {
+		if bus.txs[i].dc != tx.dc || !bytes.Equal(bus.txs[i].w, tx.w) {
+			t.Errorf("transfer %d = %v %X, want %v %X", i, bus.txs[i].dc, bus.txs[i].w, tx.dc, tx.w)
+		}
+	}
+
+	// The pin is left low, so a write only switches it for the data
+	bus.dcOuts = 0
+	if _, err := dev.Write(make([]byte, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if bus.dcOuts != 1 {
+		t.Errorf("Write DC writes = %d, want 1", bus.dcOuts)
+	}
+}
+
+func TestReuseWindow(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2, ReuseWindow: true})
+	frame := bytes.Repeat([]byte{0x12}, 8)
+	for i := 0; i < 2; i++ {
+		if _, err := dev.Write(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []fakeTx{
+		{dc: gpio.Low, w: []byte{0x15, dev.ramColumn(0), dev.ramColumn(7), 0x75, 0, 1, 0x5C}},
+		{dc: gpio.High, w: frame},
+		{dc: gpio.High, w: frame},
+	}
+	if len(bus.txs) != len(want) {
+		t.Fatalf("transfers = %d, want %d", len(bus.txs), len(want))
+	}
+	for i, tx := range want {
+		if bus.txs[i].dc != tx.dc || !bytes.Equal(bus.txs[i].w, tx.w) {
+			t.Errorf("transfer %d = %v %X, want %v %X", i, bus.txs[i].dc, bus.txs[i].w, tx.dc, tx.w)
+		}
+	}
+
+	// Any other command ends the RAM write, so the window is sent again
+	bus.reset()
+	if err := dev.SetContrast(0x80); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.txs) != 3 || bus.txs[1].w[0] != 0x15 {
+		t.Errorf("after SetContrast sent %d transfers, want contrast, window and data", len(bus.txs))
+	}
+
+	// A different window is always sent
+	bus.reset()
+	dev.Image().SetGray4(0, 0, image4bit.Gray4{Y: 0xF})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.txs) != 2 || bus.txs[0].dc != gpio.Low {
+		t.Errorf("partial write sent %d transfers, want window and data", len(bus.txs))
+	}
+
+	// Disabled by default
+	dev, bus = newTestDev(t, &Opts{W: 8, H: 2})
+	for i := 0; i < 2; i++ {
+		if _, err := dev.Write(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(bus.txs) != 4 {
+		t.Errorf("without ReuseWindow sent %d transfers, want 4", len(bus.txs))
+	}
+}
+
+func TestSaveRestoreState(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 4})
+	table := [15]byte{1, 2, 3, 4, 5, 6, 7, 8, 20, 40, 60, 80, 100, 140, 180}
+	if err := dev.SetGrayscaleTable(table); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetContrast(0x40); err != nil {
+		t.Fatal(err)
+	}
+	saved := dev.SaveState()
+
+	// A transient effect changing several settings
+	if err := dev.Invert(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetContrast(0xFF); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.UseDefaultGrayscale(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.ScrollHorizontal(0, 3, Speed10Frames, true); err != nil {
+		t.Fatal(err)
+	}
+
+	bus.reset()
+	if err := dev.RestoreState(saved); err != nil {
+		t.Fatalf("RestoreState() error = %v", err)
+	}
+	want := []byte{
+		0x2E,       // Stop the effect's scroll
+		0xA6,       // Normal display
+		0xC1, 0x40, // Original contrast
+		0xA1, 0x00, // Start line
+		0xB8, // Custom grayscale table, loaded again
+	}
+	want = append(append(want, table[:]...), 0x00)
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, want) {
+		t.Errorf("RestoreState() sent %v, want one transfer %X", bus.txs, want)
+	}
+	if got := dev.SaveState(); !reflect.DeepEqual(got, saved) {
+		t.Errorf("state after restore = %+v, want %+v", got, saved)
+	}
+
+	// A scroll in the snapshot is started again
+	if err := dev.ScrollHorizontal(1, 2, Speed6Frames, false); err != nil {
+		t.Fatal(err)
+	}
+	scrolling := dev.SaveState()
+	if err := dev.StopScroll(); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+	if err := dev.RestoreState(scrolling); err != nil {
+		t.Fatalf("RestoreState() error = %v", err)
+	}
+	wantScroll := []byte{0x26, 0x00, 1, byte(Speed6Frames), 2, 0x00, 0x00, 0x2F}
+	if len(bus.txs) != 1 || !bytes.HasSuffix(bus.txs[0].w, wantScroll) {
+		t.Errorf("RestoreState() sent %v, want scroll setup %X at the end", bus.txs, wantScroll)
+	}
+
+	// A partial display window in the snapshot is set again
+	if err := dev.StopScroll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetPartialDisplay(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	partial := dev.SaveState()
+	if err := dev.ExitPartialDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+	if err := dev.RestoreState(partial); err != nil {
+		t.Fatalf("RestoreState() error = %v", err)
+	}
+	wantPartial := []byte{0xA8, 1, 2}
+	if len(bus.txs) != 1 || !bytes.HasSuffix(bus.txs[0].w, wantPartial) {
+		t.Errorf("RestoreState() sent %v, want partial display %X at the end", bus.txs, wantPartial)
+	}
+	if got := dev.SaveState(); !reflect.DeepEqual(got, partial) {
+		t.Errorf("state after restore = %+v, want %+v", got, partial)
+	}
+
+	// and left before a snapshot's scroll is started
+	bus.reset()
+	if err := dev.RestoreState(scrolling); err != nil {
+		t.Fatalf("RestoreState() error = %v", err)
+	}
+	if len(bus.txs) != 1 || bus.txs[0].w[0] != 0xA9 || !bytes.HasSuffix(bus.txs[0].w, wantScroll) {
+		t.Errorf("RestoreState() sent %v, want A9 first and scroll setup %X at the end", bus.txs, wantScroll)
+	}
+	if dev.SaveState().Partial != nil {
+		t.Error("partial display still set after restoring a snapshot without one")
+	}
+
+	// Invalid snapshots send nothing
+	bus.reset()
+	if err := dev.RestoreState(DevState{StartLine: 200}); err == nil {
+		t.Error("RestoreState() with start line 200 should fail")
+	}
+	both := partial
+	both.Scroll = scrolling.Scroll
+	if err := dev.RestoreState(both); err == nil {
+		t.Error("RestoreState() with scroll and partial display should fail")
+	}
+	if err := dev.RestoreState(DevState{Partial: &PartialWindow{StartRow: 0, EndRow: 4}}); err == nil {
+		t.Error("RestoreState() with partial display row 4 should fail")
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("failed RestoreState sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+func TestSPISettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *Opts
+		wantFreq physic.Frequency
+		wantMode spi.Mode
+		wantErr  bool
+	}{
+		{"nil options", nil, 10 * physic.MegaHertz, spi.Mode0, false},
+		{"zero fields", &Opts{W: 8, H: 2}, 10 * physic.MegaHertz, spi.Mode0, false},
+		{"20MHz mode 3", &Opts{W: 8, H: 2, SPIHz: 20_000_000, SPIMode: spi.Mode3}, 20 * physic.MegaHertz, spi.Mode3, false},
+		{"slow", &Opts{W: 8, H: 2, SPIHz: 500_000}, 500 * physic.KiloHertz, spi.Mode0, false},
+		{"above datasheet maximum", &Opts{W: 8, H: 2, SPIHz: 20_000_001}, 0, 0, true},
+		{"negative", &Opts{W: 8, H: 2, SPIHz: -1}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeBus{}
+			_, err := NewSPI(bus, &fakeDC{bus: bus}, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(bus.txs) != 0 {
+					t.Errorf("failed NewSPI sent %d transfers, want 0", len(bus.txs))
+				}
+				return
+			}
+			if bus.freq != tt.wantFreq || bus.mode != tt.wantMode {
+				t.Errorf("Connect(%v, %v), want Connect(%v, %v)", bus.freq, bus.mode, tt.wantFreq, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestOrientation(t *testing.T) {
+	// An 8×4 panel; the pixel at logical (1, 2) must land at the native
+	// position given for each orientation
+	tests := []struct {
+		name        string
+		orientation Orientation
+		bounds      image.Rectangle
+		native      image.Point
+	}{
+		{"0", Rotate0, image.Rect(0, 0, 8, 4), image.Pt(1, 2)},
+		{"90", Rotate90, image.Rect(0, 0, 4, 8), image.Pt(2, 2)},
+		{"180", Rotate180, image.Rect(0, 0, 8, 4), image.Pt(1, 2)}, // Rotated by the remap
+		{"270", Rotate270, image.Rect(0, 0, 4, 8), image.Pt(5, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, _ := newTestDev(t, &Opts{W: 8, H: 4, Orientation: tt.orientation})
+			if got := dev.Bounds(); got != tt.bounds {
+				t.Errorf("Bounds() = %v, want %v", got, tt.bounds)
+			}
+
+			src := image.NewUniform(image4bit.Gray4{Y: 0xC})
+			if err := dev.Draw(image.Rect(1, 2, 2, 3), src, image.Point{}); err != nil {
+				t.Fatal(err)
+			}
+			want := make([]byte, 16)
+			i := tt.native.Y*4 + tt.native.X/2
+			want[i] = 0xC0 >> (4 * (tt.native.X % 2))
+			if !bytes.Equal(dev.buffer, want) {
+				t.Errorf("buffer = %X, want %X", dev.buffer, want)
+			}
+		})
+	}
+
+	// Rotate180 is the same remap as Rotated
+	r1, _ := ComputeRemap(Opts{Orientation: Rotate180})
+	if want, _ := ComputeRemap(Opts{Rotated: true}); r1 != want {
+		t.Errorf("Rotate180 remap = %02X, want %02X", r1, want)
+	}
+
+	bus := &fakeBus{}
+	if _, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 8, H: 4, Orientation: 4}); err == nil {
+		t.Error("NewSPI() with an invalid orientation should fail")
+	}
+}
+
+func TestOrientationImage(t *testing.T) {
+	// A full logical image rotates as a whole, with Write data staying
+	// native
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 2, Orientation: Rotate90})
+	img := image4bit.NewHorizontalNibble(dev.Bounds())
+	for y := 0; y < 8; y++ {
+		img.SetGray4(0, y, image4bit.Gray4{Y: uint8(y)})
+		img.SetGray4(1, y, image4bit.Gray4{Y: uint8(8 + y)})
+	}
+	if err := dev.Draw(dev.Bounds(), img, image.Point{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Logical column 0 is the bottom native row, read left to right
+	want := []byte{
+		0x89, 0xAB, 0xCD, 0xEF,
+		0x01, 0x23, 0x45, 0x67,
+	}
+	if !bytes.Equal(dev.buffer, want) {
+		t.Errorf("buffer = %X, want %X", dev.buffer, want)
+	}
+
+	// DrawDithered rotates the same way
+	gray := image.NewGray(dev.Bounds())
+	for y := 0; y < 8; y++ {
+		gray.SetGray(0, y, color.Gray{Y: uint8(y) * 0x11})
+		gray.SetGray(1, y, color.Gray{Y: uint8(8+y) * 0x11})
+	}
+	dev.Image().Fill(image4bit.Gray4{})
+	if err := dev.DrawDithered(gray); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dev.buffer, want) {
+		t.Errorf("DrawDithered buffer = %X, want %X", dev.buffer, want)
+	}
+}
+
+func TestOrientationVerticalNibble(t *testing.T) {
+	// A VerticalNibble source, whose bytes are moved whole where they line
+	// up with the frame, lands exactly where the same pixels drawn from an
+	// image.Gray do
+	tests := []struct {
+		name string
+		dst  image.Rectangle
+		sp   image.Point
+	}{
+		{"full", image.Rect(0, 0, 4, 8), image.Pt(0, 0)},
+		{"inner", image.Rect(1, 2, 3, 6), image.Pt(1, 2)},
+		{"odd rows", image.Rect(0, 1, 4, 6), image.Pt(2, 3)},
+		{"unaligned", image.Rect(0, 0, 4, 7), image.Pt(0, 1)},
+	}
+
+	for _, o := range []Orientation{Rotate90, Rotate270} {
+		for _, w := range []int{8, 7} {
+			for _, tt := range tests {
+				t.Run(fmt.Sprintf("%d/%d/%s", o*90, w, tt.name), func(t *testing.T) {
+					vn := image4bit.NewVerticalNibble(image.Rect(-1, -2, 5, 10))
+					gray := image.NewGray(vn.Rect)
+					for y := vn.Rect.Min.Y; y < vn.Rect.Max.Y; y++ {
+						for x := vn.Rect.Min.X; x < vn.Rect.Max.X; x++ {
+							c := image4bit.Gray4{Y: uint8(x*7+y*3) & 0x0F}
+							vn.SetGray4(x, y, c)
+							gray.Set(x, y, c)
+						}
+					}
+
+					dev, _ := newTestDev(t, &Opts{W: w, H: 4, Orientation: o})
+					want, _ := newTestDev(t, &Opts{W: w, H: 4, Orientation: o})
+					dst := tt.dst.Intersect(dev.Bounds())
+					if err := dev.Draw(dst, vn, tt.sp); err != nil {
+						t.Fatal(err)
+					}
+					if err := want.Draw(dst, gray, tt.sp); err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(dev.buffer, want.buffer) {
+						t.Errorf("buffer = %X, want %X", dev.buffer, want.buffer)
+					}
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkDrawRotated measures full-frame Draws on a panel mounted in
+// portrait, from a VerticalNibble and from a HorizontalNibble image.
+func BenchmarkDrawRotated(b *testing.B) {
+	bus := &fakeBus{}
+	dev, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 256, H: 64, Orientation: Rotate90})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, src := range []interface {
+		image.Image
+		SetGray4(x, y int, c image4bit.Gray4)
+	}{
+		image4bit.NewVerticalNibble(dev.Bounds()),
+		image4bit.NewHorizontalNibble(dev.Bounds()),
+	} {
+		b.Run(fmt.Sprintf("%T", src), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				src.SetGray4(i%64, i%256, image4bit.Gray4{Y: uint8(i)&0x0F | 1})
+				if err := dev.Draw(dev.Bounds(), src, image.Point{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+	img := dev.Image()
+	img.Fill(image4bit.Gray4{Y: 0x7})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetContrast(0x20); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetContrast(0x40); err == nil {
+		t.Error("SetContrast should fail when halted")
+	}
+
+	bus.reset()
+	if err := dev.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if len(bus.txs) == 0 || !bytes.HasPrefix(bus.txs[0].w, InitSequence(dev.opts)) {
+		t.Error("Reset() did not re-send the init sequence")
+	}
+	if last := bus.txs[len(bus.txs)-1].w; !bytes.Equal(last, []byte{0xAF}) {
+		t.Errorf("Reset() ended with %X, want display ON (AF)", last)
+	}
+
+	// Buffers and settings are back to their initial state
+	if !bytes.Equal(dev.buffer, make([]byte, 8)) || !bytes.Equal(dev.Image().Pix, make([]byte, 8)) {
+		t.Errorf("buffers after Reset = %X and %X, want cleared", dev.buffer, dev.Image().Pix)
+	}
+	if dev.contrast != 0xFF {
+		t.Errorf("contrast after Reset = %02X, want FF", dev.contrast)
+	}
+
+	// Operations work again, and redrawing the old frame sends all of it
+	if err := dev.SetContrast(0x40); err != nil {
+		t.Errorf("SetContrast() after Reset error = %v", err)
+	}
+	bus.reset()
+	frame := image4bit.NewHorizontalNibble(image.Rect(0, 0, 8, 2))
+	frame.Fill(image4bit.Gray4{Y: 0x7})
+	if err := dev.Draw(image.Rect(1, 0, 8, 2), frame, image.Pt(1, 0)); err != nil {
+		t.Fatalf("Draw() after Reset error = %v", err)
+	}
+	if data := bus.data(); len(data) != 1 || len(data[0]) != 8 {
+		t.Errorf("Draw() after Reset sent %X, want the whole frame", data)
+	}
+
+	// The image held across Halt and Reset is still the frame buffer
+	if got := dev.Image(); got != img {
+		t.Errorf("Image() after Reset = %p, want %p from before", got, img)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+	img.SetGray4(2, 1, image4bit.Gray4{Y: 0xF})
+	if err := dev.Flush(); err != nil {
+		t.Fatalf("Flush() after Reset error = %v", err)
+	}
+	if data := bus.data(); len(data) != 1 || !bytes.Equal(data[0], []byte{0xF0}) {
+		t.Errorf("Flush() of the held image sent %X, want [F0]", data)
+	}
+}
+
+func TestSetGrayscaleTable(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+	var gamma [15]byte
+	for i := range gamma {
+		gamma[i] = byte((i + 1) * (i + 1) * 180 / 225)
+	}
+	if err := dev.SetGrayscaleTable(gamma); err != nil {
+		t.Fatalf("SetGrayscaleTable() error = %v", err)
+	}
+	want := append(append([]byte{0xB8}, gamma[:]...), 0x00)
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, want) {
+		t.Errorf("SetGrayscaleTable() sent %v, want %X", bus.txs, want)
+	}
+	if !dev.CustomGrayscaleActive() || !bytes.Equal(dev.grayTable, gamma[:]) {
+		t.Error("SetGrayscaleTable() did not record the active custom table")
+	}
+
+	// Invalid tables are rejected without sending anything
+	bus.reset()
+	invalid := []struct {
+		name  string
+		table [15]byte
+	}{
+		{"decreasing", [15]byte{1, 2, 3, 5, 4, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+		{"repeated", [15]byte{1, 2, 3, 4, 4, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+		{"above 180", [15]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 181}},
+	}
+	for _, tt := range invalid {
+		if err := dev.SetGrayscaleTable(tt.table); err == nil {
+			t.Errorf("%s: SetGrayscaleTable() should fail", tt.name)
+		}
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("invalid tables sent %d transfers, want 0", len(bus.txs))
+	}
+
+	// Resetting restores the default table and forgets the custom one
+	if err := dev.ResetGrayscaleTable(); err != nil {
+		t.Fatalf("ResetGrayscaleTable() error = %v", err)
+	}
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xB9}) {
+		t.Errorf("ResetGrayscaleTable() sent %v, want B9", bus.txs)
+	}
+	if dev.CustomGrayscaleActive() {
+		t.Error("custom table should be inactive after ResetGrayscaleTable")
+	}
+	if err := dev.EnableGrayscaleTable(); err == nil {
+		t.Error("EnableGrayscaleTable should fail after ResetGrayscaleTable")
+	}
+}
+
+func TestSleepWake(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+	frame := bytes.Repeat([]byte{0x5A}, 8)
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+
+	bus.reset()
+	if err := dev.Sleep(); err != nil {
+		t.Fatalf("Sleep() error = %v", err)
+	}
+	if err := dev.Sleep(); err != nil {
+		t.Fatalf("second Sleep() error = %v", err)
+	}
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xAE}) {
+		t.Errorf("Sleep() sent %v, want a single AE", bus.txs)
+	}
+
+	// Drawing is rejected while sleeping, without bricking the device
+	if _, err := dev.Write(frame); err == nil {
+		t.Error("Write should fail while sleeping")
+	}
+	if err := dev.Draw(dev.Bounds(), dev.Image(), image.Point{}); err == nil {
+		t.Error("Draw should fail while sleeping")
+	}
+	if dev.halted {
+		t.Error("Sleep should not halt the device")
+	}
+
+	bus.reset()
+	if err := dev.Wake(); err != nil {
+		t.Fatalf("Wake() error = %v", err)
+	}
+	if err := dev.Wake(); err != nil {
+		t.Fatalf("second Wake() error = %v", err)
+	}
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xAF}) {
+		t.Errorf("Wake() sent %v, want a single AF", bus.txs)
+	}
+
+	// The frame survived and normal operation resumes
+	if !bytes.Equal(dev.buffer, frame) {
+		t.Errorf("buffer after Wake = %X, want %X", dev.buffer, frame)
+	}
+	if _, err := dev.Write(frame); err != nil {
+		t.Errorf("Write() after Wake error = %v", err)
+	}
+
+	// A halted device cannot be woken
+	if err := dev.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+	if err := dev.Wake(); err == nil {
+		t.Error("Wake should fail after Halt")
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("Wake after Halt sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+func TestMaxTxSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  []int
+	}{
+		{"default", 0, []int{4096, 4096}},
+		{"custom", 3000, []int{3000, 3000, 2192}},
+		{"larger than the frame", 10000, []int{8192}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeBus{}
+			dev, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 256, H: 64, MaxTxSize: tt.limit})
+			if err != nil {
+				t.Fatalf("NewSPI() error = %v", err)
+			}
+			limit := tt.want[0]
+			for i, tx := range bus.txs {
+				if len(tx.w) > limit {
+					t.Errorf("init transfer %d is %d bytes, above %d", i, len(tx.w), limit)
+				}
+			}
+
+			bus.reset()
+			if _, err := dev.Write(make([]byte, 8192)); err != nil {
+				t.Fatal(err)
+			}
+
+			// One window command, then the data in chunks with DC high
+			if len(bus.txs) != len(tt.want)+1 || bus.txs[0].dc != gpio.Low || bus.txs[0].w[0] != 0x15 {
+				t.Fatalf("Write sent %d transfers, want the window then %d chunks", len(bus.txs), len(tt.want))
+			}
+			for i, n := range tt.want {
+				tx := bus.txs[i+1]
+				if tx.dc != gpio.High || len(tx.w) != n {
+					t.Errorf("chunk %d = %d bytes with DC %v, want %d with DC high", i, len(tx.w), tx.dc, n)
+				}
+			}
+		})
+	}
+
+	bus := &fakeBus{}
+	if _, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 8, H: 2, MaxTxSize: -1}); err == nil {
+		t.Error("NewSPI() with a negative MaxTxSize should fail")
+	}
+}
+
+func TestDiffRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		changes []image.Rectangle
+		want    []image.Rectangle // nil for the bounding box fallback
+	}{
+		{
+			"opposite corners", 4,
+			[]image.Rectangle{image.Rect(0, 0, 4, 2), image.Rect(252, 62, 256, 64)},
+			[]image.Rectangle{image.Rect(0, 0, 4, 2), image.Rect(252, 62, 256, 64)},
+		},
+		{
+			"short gap in a row is resent", 4,
+			[]image.Rectangle{image.Rect(0, 0, 4, 1), image.Rect(16, 0, 20, 1)},
+			[]image.Rectangle{image.Rect(0, 0, 20, 1)},
+		},
+		{
+			"long gap in a row splits", 4,
+			[]image.Rectangle{image.Rect(0, 0, 4, 1), image.Rect(100, 0, 104, 1)},
+			[]image.Rectangle{image.Rect(0, 0, 4, 1), image.Rect(100, 0, 104, 1)},
+		},
+		{
+			"branches joined below", 4,
+			[]image.Rectangle{image.Rect(0, 0, 2, 2), image.Rect(40, 0, 42, 2), image.Rect(0, 2, 42, 3)},
+			[]image.Rectangle{image.Rect(0, 0, 42, 3)},
+		},
+		{
+			"separated by an unchanged row", 4,
+			[]image.Rectangle{image.Rect(10, 5, 20, 6), image.Rect(10, 7, 20, 8)},
+			[]image.Rectangle{image.Rect(10, 5, 20, 6), image.Rect(10, 7, 20, 8)},
+		},
+		{
+			"more regions than the limit", 2,
+			[]image.Rectangle{image.Rect(0, 0, 2, 1), image.Rect(100, 10, 102, 11), image.Rect(200, 20, 202, 21)},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, _ := newTestDev(t, &Opts{W: 256, H: 64, MaxDiffRegions: tt.limit})
+			img := dev.Image()
+			for _, r := range tt.changes {
+				img.FillRect(r, image4bit.Gray4{Y: 15})
+			}
+			dev.calculateDiff()
+			got := diffRegions(dev.diffRuns, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("diffRegions() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("diffRegions()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffRegionsCoverChanges(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 256, H: 64, MaxDiffRegions: 4})
+	img := dev.Image()
+	img.FillRect(image.Rect(0, 0, 4, 2), image4bit.Gray4{Y: 15})
+	img.FillRect(image.Rect(252, 62, 256, 64), image4bit.Gray4{Y: 15})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two windows of 2x2 bytes each, and nothing else
+	data := bus.data()
+	if len(data) != 2 || len(data[0]) != 4 || len(data[1]) != 4 {
+		t.Fatalf("Flush sent data %v, want two 4-byte writes", data)
+	}
+	if dev.lastWrite != image.Rect(252, 62, 256, 64) {
+		t.Errorf("lastWrite = %v, want the second corner", dev.lastWrite)
+	}
+	for _, d := range data {
+		if !bytes.Equal(d, []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+			t.Errorf("region data = %x, want all white", d)
+		}
+	}
+	if b, _ := dev.RegionBytes(image.Rect(252, 62, 256, 64)); !bytes.Equal(b, []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Errorf("stored frame = %x, want the corner white", b)
+	}
+
+	// Disabled by default: one bounding box spanning the whole frame
+	dev, bus = newTestDev(t, &Opts{W: 256, H: 64})
+	img = dev.Image()
+	img.FillRect(image.Rect(0, 0, 4, 2), image4bit.Gray4{Y: 15})
+	img.FillRect(image.Rect(252, 62, 256, 64), image4bit.Gray4{Y: 15})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(bytes.Join(bus.data(), nil)); n != 128*64 {
+		t.Errorf("Flush without MaxDiffRegions sent %d bytes, want the full frame", n)
+	}
+}
+
+// BenchmarkDiffTwoCorners compares the bytes transmitted for a change in
+// two opposite corners, written as one bounding box or as two regions.
+func BenchmarkDiffTwoCorners(b *testing.B) {
+	for _, bm := range []struct {
+		name    string
+		regions int
+	}{
+		{"BoundingBox", 0},
+		{"Regions", 4},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			bus := &fakeBus{}
+			dev, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 256, H: 64, MaxDiffRegions: bm.regions})
+			if err != nil {
+				b.Fatal(err)
+			}
+			img := dev.Image()
+
+			sent := 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bus.reset()
+				c := image4bit.Gray4{Y: uint8(i%2) * 15}
+				img.FillRect(image.Rect(0, 0, 8, 4), c)
+				img.FillRect(image.Rect(248, 60, 256, 64), c)
+				if err := dev.Flush(); err != nil {
+					b.Fatal(err)
+				}
+				for _, tx := range bus.txs {
+					sent += len(tx.w)
+				}
+			}
+			b.ReportMetric(float64(sent)/float64(b.N), "bytes/op")
+		})
+	}
+}
+
+func TestFirstLastDiff(t *testing.T) {
+	base := make([]byte, 21)
+	for _, tc := range []struct {
+		name        string
+		changed     []int
+		first, last int
+	}{
+		{"equal", nil, 21, -1},
+		{"first byte", []int{0}, 0, 0},
+		{"last byte", []int{20}, 20, 20},
+		{"within words", []int{3, 12}, 3, 12},
+		{"word boundaries", []int{7, 8}, 7, 8},
+		{"tail only", []int{17, 19}, 17, 19},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			other := slices.Clone(base)
+			for _, i := range tc.changed {
+				other[i] = 0x5A
+			}
+			if got := firstDiff(base, other); got != tc.first {
+				t.Errorf("firstDiff() = %d, want %d", got, tc.first)
+			}
+			if got := lastDiff(base, other); got != tc.last {
+				t.Errorf("lastDiff() = %d, want %d", got, tc.last)
+			}
+		})
+	}
+}
+
+func TestDirtyRect(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []image.Rectangle
+		want    image.Rectangle
+	}{
+		{"nothing", nil, image.Rectangle{}},
+		{"vertical line", []image.Rectangle{image.Rect(9, 1, 10, 7)}, image.Rect(8, 1, 10, 7)},
+		{"single pixel", []image.Rectangle{image.Rect(31, 7, 32, 8)}, image.Rect(30, 7, 32, 8)},
+		{"widening rows", []image.Rectangle{image.Rect(12, 0, 13, 2), image.Rect(3, 4, 4, 5), image.Rect(27, 6, 28, 7)}, image.Rect(2, 0, 28, 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The column-skipping scan must agree with the full scan used
+			// when recording details
+			for _, record := range []bool{false, true} {
+				dev, _ := newTestDev(t, &Opts{W: 32, H: 8, RecordDiffDetails: record})
+				img := dev.Image()
+				for _, r := range tt.changes {
+					img.FillRect(r, image4bit.Gray4{Y: 15})
+				}
+				if err := dev.Flush(); err != nil {
+					t.Fatal(err)
+				}
+				if got := dev.DirtyRect(); got != tt.want {
+					t.Errorf("DirtyRect() with RecordDiffDetails=%v = %v, want %v", record, got, tt.want)
+				}
+			}
+		})
+	}
+
+	// A full-frame Draw transmits everything without diffing
+	dev, _ := newTestDev(t, &Opts{W: 32, H: 8})
+	if err := dev.Draw(dev.Bounds(), image4bit.NewHorizontalNibble(dev.Bounds()), image.Point{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := dev.DirtyRect(); got != dev.Bounds() {
+		t.Errorf("DirtyRect() after a full-frame Draw = %v, want %v", got, dev.Bounds())
+	}
+
+	// An unchanged frame reports nothing
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := dev.DirtyRect(); !got.Empty() {
+		t.Errorf("DirtyRect() after an unchanged Flush = %v, want empty", got)
+	}
+}
+
+// BenchmarkCalculateDiffVerticalLine measures the diff of a 1-pixel wide,
+// full-height line, with the column-skipping scan and with the full row
+// scan needed to record details.
+func BenchmarkCalculateDiffVerticalLine(b *testing.B) {
+	for _, bm := range []struct {
+		name   string
+		record bool
+	}{
+		{"ColumnSkip", false},
+		{"FullScan", true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			bus := &fakeBus{}
+			dev, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 256, H: 64, RecordDiffDetails: bm.record})
+			if err != nil {
+				b.Fatal(err)
+			}
+			dev.Image().FillRect(image.Rect(128, 0, 129, 64), image4bit.Gray4{Y: 15})
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				dev.calculateDiff()
+			}
+		})
+	}
+}
+
+func TestBeginFrame(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 16, H: 4})
+
+	frame := dev.BeginFrame()
+	white := image.NewUniform(image4bit.Gray4{Y: 15})
+	draw.Draw(frame, image.Rect(0, 0, 2, 1), white, image.Point{}, draw.Src)
+	draw.Draw(frame, image.Rect(4, 1, 6, 2), white, image.Point{}, draw.Src)
+	draw.Draw(frame, image.Rect(2, 3, 4, 4), white, image.Point{}, draw.Src)
+	if len(bus.txs) != 0 {
+		t.Fatal("drawing into BeginFrame() should not transmit")
+	}
+
+	// The draws coalesce into one window and one data transfer
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.txs) != 2 || bus.txs[0].dc != gpio.Low || bus.txs[1].dc != gpio.High {
+		t.Fatalf("Flush sent %d transfers, want the window and its data", len(bus.txs))
+	}
+	if got, want := len(bus.txs[1].w), 3*4; got != want {
+		t.Errorf("Flush sent %d data bytes, want %d", got, want)
+	}
+
+	// The displayed frame is updated as by Draw
+	want := []byte{
+		0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	if got, _ := dev.RegionBytes(dev.Bounds()); !bytes.Equal(got, want) {
+		t.Errorf("stored frame = %X, want %X", got, want)
+	}
+	if !bytes.Equal(dev.lastDm.Pix, dev.buffer) {
+		t.Error("lastDm and buffer differ after Flush")
+	}
+
+	// The next frame starts from the displayed one
+	bus.reset()
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("Flush of an unchanged frame sent %d transfers, want 0", len(bus.txs))
+	}
+}
+
+func TestConcurrentCommands(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 32, H: 8})
+	frames := [2]*image4bit.HorizontalNibble{
+		image4bit.NewHorizontalNibble(dev.Bounds()),
+		image4bit.NewHorizontalNibble(dev.Bounds()),
+	}
+	frames[1].FillRect(image.Rect(4, 2, 10, 6), image4bit.Gray4{Y: 15})
+
+	const n = 2000
+	var wg sync.WaitGroup
+	wg.Add(2)
+	errs := make(chan error, 2*n)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			// Partial frames take the differential path, whose window
+			// commands and data are separate transfers
+			if err := dev.Draw(image.Rect(0, 0, 16, 8), frames[(i+1)%2], image.Point{}); err != nil {
+				errs <- err
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			if err := dev.SetContrast(byte(i)); err != nil {
+				errs <- err
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// Every data transfer must directly follow the write RAM command of
+	// its window; a contrast command in between would shift the stream
+	writes := 0
+	for i, tx := range bus.txs {
+		if tx.dc != gpio.High {
+			continue
+		}
+		writes++
+		if i == 0 || bus.txs[i-1].dc != gpio.Low || !bytes.HasSuffix(bus.txs[i-1].w, []byte{0x5C}) {
+			t.Fatalf("data transfer %d does not follow a RAM write command: %X", i, bus.txs[max(i-1, 0)].w)
+		}
+	}
+	if writes != n {
+		t.Errorf("Draw sent %d data transfers, want %d", writes, n)
+	}
+}
+
+func TestConcurrentBounds(t *testing.T) {
+	// Bounds is read while Reconfigure changes the size; run with -race
+	dev, _ := newTestDev(t, &Opts{W: 32, H: 8})
+	sizes := [2]image.Rectangle{image.Rect(0, 0, 32, 8), image.Rect(0, 0, 16, 4)}
+
+	const n = 200
+	var wg sync.WaitGroup
+	wg.Add(1)
+	errs := make(chan error, n)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			s := sizes[(i+1)%2]
+			if err := dev.Reconfigure(s.Dx(), s.Dy()); err != nil {
+				errs <- err
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		if b := dev.Bounds(); b != sizes[0] && b != sizes[1] {
+			t.Errorf("Bounds() = %v, want one of %v", b, sizes)
+			break
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentSetClock(t *testing.T) {
+	// Timed operations wait without the lock while SetClock replaces the
+	// clock; run with -race
+	noSleep := Clock{Sleep: func(time.Duration) {}}
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 2, Clock: noSleep})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	errs := make(chan error, 2)
+	go func() {
+		defer wg.Done()
+		if err := dev.FadeContrast(0x00, time.Second); err != nil {
+			errs <- err
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		err := dev.ScrollVertical(ctx, image.NewUniform(image4bit.Gray4{Y: 3}), 1, time.Millisecond)
+		if !errors.Is(err, context.Canceled) {
+			errs <- err
+		}
+	}()
+	if err := dev.StartAutoFlush(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		dev.SetClock(noSleep)
+	}
+	cancel()
+	wg.Wait()
+	if err := dev.StopAutoFlush(); err != nil {
+		t.Error(err)
+	}
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(dev *Dev) error
+		want error
+	}{
+		{"halted", func(dev *Dev) error {
+			if err := dev.Halt(); err != nil {
+				return err
+			}
+			return dev.SetContrast(0x80)
+		}, ErrHalted},
+		{"sleeping", func(dev *Dev) error {
+			if err := dev.Sleep(); err != nil {
+				return err
+			}
+			_, err := dev.Write(make([]byte, 16))
+			return err
+		}, ErrSleeping},
+		{"buffer size", func(dev *Dev) error {
+			_, err := dev.Write(make([]byte, 15))
+			return err
+		}, ErrInvalidBufferSize},
+		{"SetBuffer size", func(dev *Dev) error {
+			return dev.SetBuffer(make([]byte, 17))
+		}, ErrInvalidBufferSize},
+		{"region bounds", func(dev *Dev) error {
+			_, err := dev.RegionBytes(image.Rect(0, 0, 10, 2))
+			return err
+		}, ErrInvalidBounds},
+		{"scroll row", func(dev *Dev) error {
+			return dev.ScrollHorizontal(0, 200, Speed6Frames, true)
+		}, ErrScrollRowOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, _ := newTestDev(t, &Opts{W: 8, H: 4})
+			err := tt.op(dev)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := (&Dev{}).Flush(); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Flush() on an uninitialized device error = %v, want %v", err, ErrNotInitialized)
+	}
+}
+
+func TestSetRotated(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Opts
+		rotated bool
+		want    []byte
+	}{
+		{"rotate", Opts{W: 256, H: 64}, true, []byte{0xA0, 0x06, 0x11, 0xA1, 0x00}},
+		{"upright", Opts{W: 256, H: 64, Rotated: true}, false, []byte{0xA0, 0x14, 0x11, 0xA1, 0x00}},
+		{"keeps mirroring", Opts{W: 256, H: 64, MirrorX: true}, true, []byte{0xA0, 0x04, 0x11, 0xA1, 0x00}},
+		{"with Rotate180", Opts{W: 256, H: 64, Orientation: Rotate180}, true, []byte{0xA0, 0x14, 0x11, 0xA1, 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			dev, bus := newTestDev(t, &opts)
+			frame := dev.Image()
+			frame.SetGray4(0, 0, image4bit.Gray4{Y: 0xF})
+			if err := dev.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			bus.reset()
+
+			if err := dev.SetRotated(tt.rotated); err != nil {
+				t.Fatalf("SetRotated(%v) error = %v", tt.rotated, err)
+			}
+			if len(bus.txs) == 0 || !bytes.Equal(bus.txs[0].w, tt.want) {
+				t.Fatalf("SetRotated(%v) sent %v, want commands %X first", tt.rotated, bus.txs, tt.want)
+			}
+
+			// The displayed frame is re-sent, not cleared
+			if n := len(bytes.Join(bus.data(), nil)); n != 128*64 {
+				t.Errorf("SetRotated re-sent %d bytes, want the full frame", n)
+			}
+			if got := bus.data()[0][0]; got != 0xF0 {
+				t.Errorf("re-sent frame starts with %X, want F0", got)
+			}
+		})
+	}
+
+	// The start line is preserved
+	dev, bus := newTestDev(t, &Opts{W: 256, H: 64})
+	if err := dev.setStartLine(5); err != nil {
+		t.Fatal(err)
+	}
+	bus.reset()
+	if err := dev.SetRotated(true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(bus.txs[0].w, []byte{0xA1, 5}) {
+		t.Errorf("SetRotated sent %X, want start line 5", bus.txs[0].w)
+	}
+
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetRotated(false); !errors.Is(err, ErrHalted) {
+		t.Errorf("SetRotated() after Halt error = %v, want %v", err, ErrHalted)
+	}
+}
+
+func TestPartialDisplay(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 256, H: 64})
+
+	if err := dev.SetPartialDisplay(8, 23); err != nil {
+		t.Fatalf("SetPartialDisplay() error = %v", err)
+	}
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xA8, 8, 23}) {
+		t.Errorf("SetPartialDisplay sent %v, want A8 08 17", bus.txs)
+	}
+
+	// Scrolling is rejected while the window is set
+	if err := dev.ScrollHorizontal(0, 7, Speed6Frames, true); err == nil {
+		t.Error("ScrollHorizontal() with a partial display window should fail")
+	}
+
+	bus.reset()
+	if err := dev.ExitPartialDisplay(); err != nil {
+		t.Fatalf("ExitPartialDisplay() error = %v", err)
+	}
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xA9}) {
+		t.Errorf("ExitPartialDisplay sent %v, want A9", bus.txs)
+	}
+	if err := dev.ScrollHorizontal(0, 7, Speed6Frames, true); err != nil {
+		t.Errorf("ScrollHorizontal() after ExitPartialDisplay error = %v", err)
+	}
+
+	// And the window while scrolling
+	if err := dev.SetPartialDisplay(0, 7); err == nil {
+		t.Error("SetPartialDisplay() while scrolling should fail")
+	}
+	if err := dev.StopScroll(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		start, end byte
+		wantErr    bool
+	}{
+		{"single row", 5, 5, false},
+		{"whole panel", 0, 63, false},
+		{"end past panel", 0, 64, true},
+		{"start past panel", 64, 70, true},
+		{"reversed", 20, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus.reset()
+			err := dev.SetPartialDisplay(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetPartialDisplay(%d, %d) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			}
+			if tt.wantErr && len(bus.txs) != 0 {
+				t.Errorf("invalid SetPartialDisplay sent %d transfers", len(bus.txs))
+			}
+		})
+	}
+}
+
+func TestScrollVertical(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 4, Clock: clk.clock()})
+
+	// Each source row has its own level, one more than its index
+	src := image4bit.NewHorizontalNibble(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		src.FillRect(image.Rect(0, y, 8, y+1), image4bit.Gray4{Y: uint8(y + 1)})
+	}
+
+	// Cancel once 125ms have passed on the device clock
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sleep := dev.clock.Sleep
+	dev.clock.Sleep = func(d time.Duration) {
+		sleep(d)
+		if clk.now.Sub(time.Unix(0, 0)) >= 125*time.Millisecond {
+			cancel()
+		}
+	}
+
+	err := dev.ScrollVertical(ctx, src, 3, 50*time.Millisecond)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScrollVertical() error = %v, want context.Canceled", err)
+	}
+
+	// Frames at 0, 50 and 100ms; the next wait reaches 150ms and is cancelled
+	if got := len(bus.data()); got != 3 {
+		t.Errorf("ScrollVertical drew %d frames, want 3", got)
+	}
+	if got, want := clk.now.Sub(time.Unix(0, 0)), 150*time.Millisecond; got != want {
+		t.Errorf("stopped at %v, want %v", got, want)
+	}
+
+	// The third window starts at row 6 and wraps around to the top
+	for y, level := range []uint8{7, 8, 1, 2} {
+		if got := dev.Image().Gray4At(3, y).Y; got != level {
+			t.Errorf("row %d level = %d, want %d", y, got, level)
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		src      image.Image
+		step     int
+		interval time.Duration
+	}{
+		{"zero step", src, 0, time.Second},
+		{"zero interval", src, 1, 0},
+		{"short source", image4bit.NewHorizontalNibble(image.Rect(0, 0, 8, 3)), 1, time.Second},
+	} {
+		if err := dev.ScrollVertical(context.Background(), tt.src, tt.step, tt.interval); err == nil {
+			t.Errorf("ScrollVertical() with %s succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestPlayGIF(t *testing.T) {
+	palette := color.Palette{color.Gray{}, color.Gray{Y: 0x88}, color.White, color.RGBA{}}
+	frame := func(r image.Rectangle, index uint8) *image.Paletted {
+		img := image.NewPaletted(r, palette)
+		for i := range img.Pix {
+			img.Pix[i] = index
+		}
+		return img
+	}
+
+	// A gray background, a white square with the disposal under test, then
+	// a white bar elsewhere whose transparent pixels show what is left
+	anim := func(disposal byte, loops int) *gif.GIF {
+		bar := frame(image.Rect(0, 0, 8, 1), 3)
+		bar.SetColorIndex(6, 0, 2)
+		bar.SetColorIndex(7, 0, 2)
+		return &gif.GIF{
+			Image:     []*image.Paletted{frame(image.Rect(0, 0, 8, 4), 1), frame(image.Rect(2, 1, 4, 3), 2), bar},
+			Delay:     []int{10, 5, 20},
+			Disposal:  []byte{gif.DisposalNone, disposal, gif.DisposalNone},
+			LoopCount: loops,
+			Config:    image.Config{Width: 8, Height: 4},
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		disposal byte
+		want     uint8
+	}{
+		{"none", gif.DisposalNone, 15},
+		{"background", gif.DisposalBackground, 0},
+		{"previous", gif.DisposalPrevious, 8},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := &fakeClock{now: time.Unix(0, 0)}
+			dev, bus := newTestDev(t, &Opts{W: 8, H: 4, Clock: clk.clock()})
+			var sleeps []time.Duration
+			sleep := dev.clock.Sleep
+			dev.clock.Sleep = func(d time.Duration) {
+				sleeps = append(sleeps, d)
+				sleep(d)
+			}
+
+			if err := dev.PlayGIF(context.Background(), anim(tt.disposal, -1)); err != nil {
+				t.Fatalf("PlayGIF() error = %v", err)
+			}
+			if got := len(bus.data()); got != 3 {
+				t.Errorf("PlayGIF drew %d frames, want 3", got)
+			}
+			if want := []time.Duration{100 * time.Millisecond, 50 * time.Millisecond, 200 * time.Millisecond}; !reflect.DeepEqual(sleeps, want) {
+				t.Errorf("delays = %v, want %v", sleeps, want)
+			}
+			img := dev.Image()
+			for _, p := range []struct {
+				x, y int
+				want uint8
+			}{{0, 0, 8}, {6, 0, 15}, {2, 1, tt.want}, {3, 2, tt.want}} {
+				if got := img.Gray4At(p.x, p.y).Y; got != p.want {
+					t.Errorf("level at (%d, %d) = %d, want %d", p.x, p.y, got, p.want)
+				}
+			}
+		})
+	}
+
+	t.Run("loops", func(t *testing.T) {
+		clk := &fakeClock{now: time.Unix(0, 0)}
+		dev, _ := newTestDev(t, &Opts{W: 8, H: 4, Clock: clk.clock()})
+		if err := dev.PlayGIF(context.Background(), anim(gif.DisposalNone, 2)); err != nil {
+			t.Fatalf("PlayGIF() error = %v", err)
+		}
+		if got, want := clk.now.Sub(time.Unix(0, 0)), 3*350*time.Millisecond; got != want {
+			t.Errorf("played for %v, want %v", got, want)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		clk := &fakeClock{now: time.Unix(0, 0)}
+		dev, bus := newTestDev(t, &Opts{W: 8, H: 4, Clock: clk.clock()})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sleep := dev.clock.Sleep
+		dev.clock.Sleep = func(d time.Duration) {
+			sleep(d)
+			if clk.now.Sub(time.Unix(0, 0)) >= 150*time.Millisecond {
+				cancel()
+			}
+		}
+
+		// A LoopCount of 0 repeats until cancelled after the second frame
+		err := dev.PlayGIF(ctx, anim(gif.DisposalNone, 0))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("PlayGIF() error = %v, want context.Canceled", err)
+		}
+		if got := len(bus.data()); got != 2 {
+			t.Errorf("PlayGIF drew %d frames, want 2", got)
+		}
+	})
+
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 4})
+	for _, tt := range []struct {
+		name string
+		g    *gif.GIF
+	}{
+		{"no frames", &gif.GIF{}},
+		{"missing delays", &gif.GIF{Image: []*image.Paletted{frame(image.Rect(0, 0, 8, 4), 1)}}},
+	} {
+		if err := dev.PlayGIF(context.Background(), tt.g); err == nil {
+			t.Errorf("PlayGIF() with %s succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestSetMasterContrast(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	for _, level := range []byte{0, 7, 15} {
+		bus.reset()
+		if err := dev.SetMasterContrast(level); err != nil {
+			t.Fatalf("SetMasterContrast(%d) error = %v", level, err)
+		}
+		if len(bus.txs) != 1 || bus.txs[0].dc != gpio.Low || !bytes.Equal(bus.txs[0].w, []byte{0xC7, level}) {
+			t.Errorf("SetMasterContrast(%d) sent %v, want C7 %02X", level, bus.txs, level)
+		}
+	}
+
+	for _, level := range []byte{16, 0xFF} {
+		bus.reset()
+		if err := dev.SetMasterContrast(level); err == nil {
+			t.Errorf("SetMasterContrast(%d) succeeded, want error", level)
+		}
+		if len(bus.txs) != 0 {
+			t.Errorf("SetMasterContrast(%d) sent %d transfers, want none", level, len(bus.txs))
+		}
+	}
+}
+
+func TestClockSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Opts
+		want    byte
+		wantErr bool
+	}{
+		{"defaults", Opts{W: 8, H: 2}, 0xF2, false},
+		{"divider", Opts{W: 8, H: 2, ClockDivider: 1}, 0xF1, false},
+		{"oscillator", Opts{W: 8, H: 2, OscFreq: 9}, 0x92, false},
+		{"both", Opts{W: 8, H: 2, ClockDivider: 10, OscFreq: 3}, 0x3A, false},
+		{"reserved divider", Opts{W: 8, H: 2, ClockDivider: 11}, 0, true},
+		{"oscillator too large", Opts{W: 8, H: 2, OscFreq: 16}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeBus{}
+			opts := tt.opts
+			_, err := NewSPI(bus, &fakeDC{bus: bus}, &opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !bytes.Contains(bus.txs[0].w, []byte{0xAE, 0xB3, tt.want}) {
+				t.Errorf("init sent %X, want clock byte %02X", bus.txs[0].w, tt.want)
+			}
+		})
+	}
+
+	// At runtime zero values are sent as given
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+	if err := dev.SetClockDivider(0, 0); err != nil {
+		t.Fatalf("SetClockDivider(0, 0) error = %v", err)
+	}
+	if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xB3, 0x00}) {
+		t.Errorf("SetClockDivider(0, 0) sent %v, want B3 00", bus.txs)
+	}
+	bus.reset()
+	if err := dev.SetClockDivider(11, 0); err == nil {
+		t.Error("SetClockDivider(11, 0) succeeded, want error")
+	}
+	if err := dev.SetClockDivider(0, 16); err == nil {
+		t.Error("SetClockDivider(0, 16) succeeded, want error")
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("invalid SetClockDivider sent %d transfers", len(bus.txs))
+	}
+}
+
+func TestSetStartLine(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	for _, line := range []byte{0, 40, 127} {
+		bus.reset()
+		if err := dev.SetStartLine(line); err != nil {
+			t.Fatalf("SetStartLine(%d) error = %v", line, err)
+		}
+		if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xA1, line}) {
+			t.Errorf("SetStartLine(%d) sent %v, want A1 %02X", line, bus.txs, line)
+		}
+		if got := dev.SaveState().StartLine; got != line {
+			t.Errorf("SaveState().StartLine = %d, want %d", got, line)
+		}
+	}
+
+	bus.reset()
+	for _, line := range []byte{128, 0xFF} {
+		if err := dev.SetStartLine(line); err == nil {
+			t.Errorf("SetStartLine(%d) succeeded, want error", line)
+		}
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("invalid SetStartLine sent %d transfers", len(bus.txs))
+	}
+}
+
+func TestSetDisplayOffset(t *testing.T) {
+	dev, bus := newTestDev(t, &Opts{W: 8, H: 2})
+
+	for _, offset := range []byte{0, 16, 127} {
+		bus.reset()
+		if err := dev.SetDisplayOffset(offset); err != nil {
+			t.Fatalf("SetDisplayOffset(%d) error = %v", offset, err)
+		}
+		if len(bus.txs) != 1 || !bytes.Equal(bus.txs[0].w, []byte{0xA2, offset}) {
+			t.Errorf("SetDisplayOffset(%d) sent %v, want A2 %02X", offset, bus.txs, offset)
+		}
+	}
+
+	bus.reset()
+	for _, offset := range []byte{128, 0xFF} {
+		if err := dev.SetDisplayOffset(offset); err == nil {
+			t.Errorf("SetDisplayOffset(%d) succeeded, want error", offset)
+		}
+	}
+	if len(bus.txs) != 0 {
+		t.Errorf("invalid SetDisplayOffset sent %d transfers", len(bus.txs))
+	}
+}
+
+func TestLastFrameChecksum(t *testing.T) {
+	dev, _ := newTestDev(t, &Opts{W: 8, H: 2})
+	if got, want := dev.LastFrameChecksum(), crc32.ChecksumIEEE(make([]byte, 8)); got != want {
+		t.Errorf("LastFrameChecksum() after init = %08X, want %08X", got, want)
+	}
+
+	frame := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF}
+	if _, err := dev.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	want := crc32.ChecksumIEEE(frame)
+	if got := dev.LastFrameChecksum(); got != want {
+		t.Errorf("LastFrameChecksum() after Write = %08X, want %08X", got, want)
+	}
+
+	// Pending changes count only once transmitted
+	dev.Image().SetGray4(0, 0, image4bit.Gray4{Y: 0xF})
+	if got := dev.LastFrameChecksum(); got != want {
+		t.Errorf("LastFrameChecksum() with a pending change = %08X, want %08X", got, want)
+	}
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	frame[0] = 0xF1
+	if got, want := dev.LastFrameChecksum(), crc32.ChecksumIEEE(frame); got != want {
+		t.Errorf("LastFrameChecksum() after Flush = %08X, want %08X", got, want)
+	}
+
+	// A failed transmit leaves it unchanged
+	bus := &fakeBus{}
+	dev, err := NewSPI(bus, &fakeDC{bus: bus}, &Opts{W: 8, H: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.err = errors.New("bus error")
+	if _, err := dev.Write(frame); err == nil {
+		t.Fatal("Write() with a failing bus succeeded")
+	}
+	if got, want := dev.LastFrameChecksum(), crc32.ChecksumIEEE(make([]byte, 8)); got != want {
+		t.Errorf("LastFrameChecksum() after a failed Write = %08X, want %08X", got, want)
+	}
+}
+
+func TestOnCommand(t *testing.T) {
+	var trace []fakeTx
+	opts := &Opts{W: 8, H: 2, OnCommand: func(isData bool, payload []byte) {
+		dc := gpio.Low
+		if isData {
+			dc = gpio.High
+		}
+		trace = append(trace, fakeTx{dc: dc, w: payload})
+	}}
+	bus := &fakeBus{}
+	dev, err := NewSPI(bus, &fakeDC{bus: bus}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hook sees the init stream in order, exactly as transmitted
+	if !bytes.HasPrefix(trace[0].w, InitSequence(*opts)) {
+		t.Errorf("first traced payload = %X, want the init sequence", trace[0].w)
+	}
+	if !reflect.DeepEqual(trace, bus.txs) {
+		t.Errorf("traced init %v, transmitted %v", trace, bus.txs)
+	}
+
+	trace, bus.txs = nil, nil
+	if err := dev.SetContrast(0x42); err != nil {
+		t.Fatal(err)
+	}
+	dev.Image().SetGray4(3, 1, image4bit.Gray4{Y: 0xC})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace) != 3 || !reflect.DeepEqual(trace, bus.txs) {
+		t.Errorf("traced %v, transmitted %v", trace, bus.txs)
+	}
+
+	// The payload is a copy
+	trace[0].w[0] = 0
+	if bus.txs[0].w[0] != 0xC1 {
+		t.Error("modifying the traced payload changed the transmitted bytes")
+	}
+}
+
+func TestParallelTransportStrobe(t *testing.T) {
+	fp := &fakeParallel{cs: gpio.High, wr: gpio.High}
+	data, wr, _, dc, cs := fp.pins()
+	bus := &parallelTransport{data: data, wr: wr, cs: cs, dc: dcPin{pin: dc}}
+
+	// 0xA5 sets D0, D2, D5 and D7; the first byte writes DC and every data
+	// pin
+	if err := bus.SendCommand([]byte{0xA5}); err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	want := []string{"DC=L", "CS=L", "D0=H", "D1=L", "D2=H", "D3=L", "D4=L", "D5=H", "D6=L", "D7=H", "WR=L", "WR=H", "CS=H"}
+	if !reflect.DeepEqual(fp.log, want) {
+		t.Errorf("pin writes = %v, want %v", fp.log, want)
+	}
+
+	// Later bytes only change the pins that differ
+	fp.log = nil
+	if err := bus.SendCommand([]byte{0xA4, 0xA4}); err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	want = []string{"CS=L", "D0=L", "WR=L", "WR=H", "WR=L", "WR=H", "CS=H"}
+	if !reflect.DeepEqual(fp.log, want) {
+		t.Errorf("pin writes = %v, want %v", fp.log, want)
+	}
+
+	// Data raises DC before asserting CS
+	fp.log = nil
+	if err := bus.SendData([]byte{0xA4}); err != nil {
+		t.Fatalf("SendData() error = %v", err)
+	}
+	want = []string{"DC=H", "CS=L", "WR=L", "WR=H", "CS=H"}
+	if !reflect.DeepEqual(fp.log, want) {
+		t.Errorf("pin writes = %v, want %v", fp.log, want)
+	}
+	if got, want := fp.bytes(gpio.Low), []byte{0xA5, 0xA4, 0xA4}; !bytes.Equal(got, want) {
+		t.Errorf("latched commands = %X, want %X", got, want)
+	}
+	if got, want := fp.bytes(gpio.High), []byte{0xA4}; !bytes.Equal(got, want) {
+		t.Errorf("latched data = %X, want %X", got, want)
+	}
+}
+
+func TestNewParallel(t *testing.T) {
+	fp := &fakeParallel{}
+	data, wr, rd, dc, cs := fp.pins()
+	dev, err := NewParallel(data, wr, rd, dc, cs, &Opts{W: 8, H: 2})
+	if err != nil {
+		t.Fatalf("NewParallel() error = %v", err)
+	}
+
+	// The panel sees the same init sequence as over SPI, with RD left idle
+	// and CS released between transfers
+	if got, want := fp.bytes(gpio.Low), InitSequence(Opts{W: 8, H: 2}); !bytes.HasPrefix(got, want) {
+		t.Errorf("init commands = %X, want prefix %X", got, want)
+	}
+	if fp.readPulse {
+		t.Error("RD was driven low")
+	}
+	if fp.cs != gpio.High || fp.wr != gpio.High {
+		t.Errorf("idle CS = %v, WR = %v, want both High", fp.cs, fp.wr)
+	}
+
+	fp.latched = nil
+	if err := dev.SetContrast(0x40); err != nil {
+		t.Fatalf("SetContrast() error = %v", err)
+	}
+	if _, err := dev.Write([]byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got, want := fp.bytes(gpio.Low)[:2], []byte{0xC1, 0x40}; !bytes.Equal(got, want) {
+		t.Errorf("contrast command = %X, want %X", got, want)
+	}
+	if got, want := fp.bytes(gpio.High), []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0}; !bytes.Equal(got, want) {
+		t.Errorf("pixel data = %X, want %X", got, want)
+	}
+
+	if _, err := dev.ReadRAM(image.Rect(0, 0, 8, 2)); err == nil {
+		t.Error("ReadRAM() over the parallel bus succeeded, want error")
+	}
+
+	data[3] = nil
+	if _, err := NewParallel(data, wr, rd, dc, cs, nil); err == nil {
+		t.Error("NewParallel() with a nil data pin succeeded, want error")
+	}
+}
@@ -0,0 +1,126 @@
+// Package testutil provides helpers for testing display drivers built on,
+// or wrapping, the ssd1322 package.
+package testutil
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"testing"
+
+	"periph.io/x/conn/v3/display"
+)
+
+// CheckDrawer exercises d against the periph.io display.Drawer contract and
+// reports any violation through t. read returns the pixel d currently
+// shows at (x, y) in Bounds coordinates, e.g. the At method of the image
+// a fake display draws into.
+//
+// It checks that Bounds is non-empty, that ColorModel converts colors, and
+// that Draw accepts full, partial, clipped, empty and out-of-bounds
+// destination rectangles with several source image types. After each Draw
+// every pixel read back must match what draw.Draw with draw.Src would
+// produce, in d's color model: drawn pixels are updated, and empty or
+// out-of-bounds draws change nothing. If d also implements io.Writer, Write
+// is checked against the io.Writer contract, and a Write that fails must
+// leave the pixels unchanged.
+//
+// CheckDrawer draws to the device but never calls Halt.
+func CheckDrawer(t testing.TB, d display.Drawer, read func(x, y int) color.Color) {
+	t.Helper()
+
+	if d.String() == "" {
+		t.Error("String() returned an empty string")
+	}
+
+	b := d.Bounds()
+	if b.Empty() {
+		t.Fatalf("Bounds() = %v, want a non-empty rectangle", b)
+	}
+
+	m := d.ColorModel()
+	if m == nil {
+		t.Fatal("ColorModel() returned nil")
+	}
+	if c := m.Convert(color.White); c == nil {
+		t.Error("ColorModel().Convert(color.White) returned nil")
+	}
+
+	gray := image.NewGray(b)
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i)
+	}
+	half := image.Rect(b.Min.X, b.Min.Y, b.Min.X+b.Dx()/2, b.Max.Y)
+
+	draws := []struct {
+		name string
+		dst  image.Rectangle
+		src  image.Image
+		sp   image.Point
+	}{
+		{"full frame uniform", b, image.NewUniform(color.White), image.Point{}},
+		{"full frame RGBA", b, image.NewRGBA(b), b.Min},
+		{"full frame Gray", b, gray, b.Min},
+		{"full frame native model", b, image.NewUniform(m.Convert(color.Black)), image.Point{}},
+		{"partial", half, gray, half.Min},
+		{"source offset", half, gray, b.Min.Add(image.Pt(1, 1))},
+		{"clipped", b.Add(image.Pt(b.Dx()/2, b.Dy()/2)), gray, b.Min},
+		{"empty", image.Rectangle{}, gray, image.Point{}},
+		{"out of bounds", b.Add(image.Pt(b.Dx(), b.Dy())), gray, image.Point{}},
+		{"negative out of bounds", b.Sub(image.Pt(2*b.Dx(), 0)), gray, image.Point{}},
+	}
+	// The expected display content, starting from whatever d shows now
+	want := image.NewRGBA64(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			want.Set(x, y, read(x, y))
+		}
+	}
+
+	for _, tt := range draws {
+		if err := d.Draw(tt.dst, tt.src, tt.sp); err != nil {
+			t.Errorf("Draw(%s) error = %v", tt.name, err)
+			continue
+		}
+		draw.Draw(want, tt.dst, tt.src, tt.sp, draw.Src)
+		checkPixels(t, "Draw("+tt.name+")", b, m, want, read)
+	}
+
+	if w, ok := d.(io.Writer); ok {
+		for _, p := range [][]byte{nil, {0xFF}} {
+			n, err := w.Write(p)
+			if n < 0 || n > len(p) {
+				t.Errorf("Write(%d bytes) = %d, want 0 <= n <= %d", len(p), n, len(p))
+			}
+			if n < len(p) && err == nil {
+				t.Errorf("Write(%d bytes) = %d with nil error, want error for short write", len(p), n)
+			}
+			if err != nil {
+				checkPixels(t, "failed Write", b, m, want, read)
+			}
+		}
+	}
+}
+
+// checkPixels reports the first pixel of b where read differs from want
+// once both are converted to m.
+func checkPixels(t testing.TB, name string, b image.Rectangle, m color.Model, want image.Image, read func(x, y int) color.Color) {
+	t.Helper()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			got, exp := m.Convert(read(x, y)), m.Convert(want.At(x, y))
+			if !sameColor(got, exp) {
+				t.Errorf("after %s, pixel (%d, %d) = %v, want %v", name, x, y, got, exp)
+				return
+			}
+		}
+	}
+}
+
+// sameColor reports whether a and b have the same RGBA values.
+func sameColor(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
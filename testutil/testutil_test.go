@@ -0,0 +1,83 @@
+package testutil
+
+import (
+	"fmt"
+	"image"
+	"testing"
+
+	"github.com/flavioheleno/ssd1322"
+	"periph.io/x/conn/v3"
+	"periph.io/x/conn/v3/display"
+	"periph.io/x/conn/v3/display/displaytest"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/spi"
+)
+
+// fakeBus is an SPI port and DC pin that accepts and discards all traffic.
+type fakeBus struct{}
+
+func (fakeBus) String() string                                              { return "fakeBus" }
+func (fakeBus) Halt() error                                                 { return nil }
+func (fakeBus) Name() string                                                { return "DC" }
+func (fakeBus) Number() int                                                 { return -1 }
+func (fakeBus) Function() string                                            { return "Out" }
+func (fakeBus) PWM(gpio.Duty, physic.Frequency) error                       { return nil }
+func (fakeBus) Out(gpio.Level) error                                        { return nil }
+func (fakeBus) Tx(w, r []byte) error                                        { return nil }
+func (fakeBus) TxPackets(p []spi.Packet) error                              { return nil }
+func (fakeBus) Duplex() conn.Duplex                                         { return conn.Half }
+func (b fakeBus) Connect(physic.Frequency, spi.Mode, int) (spi.Conn, error) { return b, nil }
+
+func TestCheckDrawerDev(t *testing.T) {
+	for _, opts := range []*ssd1322.Opts{nil, {W: 128, H: 64}, {W: 2, H: 1}} {
+		dev, err := ssd1322.NewSPI(fakeBus{}, fakeBus{}, opts)
+		if err != nil {
+			t.Fatalf("NewSPI() error = %v", err)
+		}
+		CheckDrawer(t, dev, dev.Image().At)
+	}
+}
+
+func TestCheckDrawerDisplaytest(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	CheckDrawer(t, &displaytest.Drawer{Img: img}, img.At)
+}
+
+// lazyDrawer accepts every Draw without drawing anything.
+type lazyDrawer struct{ displaytest.Drawer }
+
+func (*lazyDrawer) Draw(image.Rectangle, image.Image, image.Point) error { return nil }
+
+// unclippedDrawer treats an empty destination as the whole display.
+type unclippedDrawer struct{ displaytest.Drawer }
+
+func (d *unclippedDrawer) Draw(r image.Rectangle, src image.Image, sp image.Point) error {
+	if r.Empty() {
+		r = d.Bounds()
+	}
+	return d.Drawer.Draw(r, src, sp)
+}
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Error(args ...any)                 { r.errs = append(r.errs, fmt.Sprint(args...)) }
+func (r *recorder) Errorf(format string, args ...any) { r.Error(fmt.Sprintf(format, args...)) }
+
+func TestCheckDrawerCatchesViolations(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for _, d := range []display.Drawer{
+		&lazyDrawer{displaytest.Drawer{Img: img}},
+		&unclippedDrawer{displaytest.Drawer{Img: img}},
+	} {
+		r := &recorder{TB: t}
+		CheckDrawer(r, d, img.At)
+		if len(r.errs) == 0 {
+			t.Errorf("CheckDrawer(%T) reported no errors", d)
+		}
+	}
+}